@@ -0,0 +1,136 @@
+package api
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// Antivirus product classes returned in AntivirusProduct.ProductClass.
+const (
+	AntivirusClassConsumerAV    = "consumer_av"
+	AntivirusClassEnterpriseEPP = "enterprise_epp"
+	AntivirusClassEDR           = "edr"
+	AntivirusClassUnknown       = "unknown"
+)
+
+//go:embed data/antivirus_vendors.json
+var antivirusCatalogFile []byte
+
+// antivirusCatalog is the embedded catalog mapping raw antivirus product names
+// to a canonical vendor and product class.
+type antivirusCatalog struct {
+	Version  string                  `json:"version"`
+	Products []antivirusCatalogEntry `json:"products"`
+}
+
+type antivirusCatalogEntry struct {
+	Vendor       string   `json:"vendor"`
+	ProductClass string   `json:"product_class"`
+	Match        []string `json:"match"`
+}
+
+var defaultAntivirusCatalog = mustLoadAntivirusCatalog(antivirusCatalogFile)
+
+func mustLoadAntivirusCatalog(data []byte) antivirusCatalog {
+	var catalog antivirusCatalog
+	if err := json.Unmarshal(data, &catalog); err != nil {
+		panic(fmt.Sprintf("invalid embedded antivirus catalog: %v", err))
+	}
+	return catalog
+}
+
+// AntivirusCatalogVersion returns the version of the embedded antivirus
+// vendor catalog.
+func AntivirusCatalogVersion() string {
+	return defaultAntivirusCatalog.Version
+}
+
+// AntivirusProduct is a raw antivirus product name resolved against the
+// vendor catalog.
+type AntivirusProduct struct {
+	Name         string `json:"name"`
+	Vendor       string `json:"vendor,omitempty"`
+	ProductClass string `json:"product_class"`
+}
+
+// NormalizeAntivirus resolves a free-form antivirus product name, such as
+// "Windows Defender" or "Microsoft Defender Antivirus", to its canonical
+// vendor and product class. When several catalog patterns match, the longest
+// one wins, so "Defender for Endpoint" resolves to an EDR rather than to
+// consumer Defender. Unknown names have an empty vendor and the unknown class.
+func NormalizeAntivirus(name string) AntivirusProduct {
+	product := AntivirusProduct{Name: name, ProductClass: AntivirusClassUnknown}
+
+	normalized := " " + normalizeAntivirusName(name) + " "
+	best := 0
+	for _, entry := range defaultAntivirusCatalog.Products {
+		for _, pattern := range entry.Match {
+			if len(pattern) > best && strings.Contains(normalized, " "+pattern+" ") {
+				best = len(pattern)
+				product.Vendor = entry.Vendor
+				product.ProductClass = entry.ProductClass
+			}
+		}
+	}
+	return product
+}
+
+// normalizeAntivirusName lower-cases the name and collapses everything other
+// than letters, digits, '.' and '-' into single spaces.
+func normalizeAntivirusName(name string) string {
+	fields := strings.FieldsFunc(strings.ToLower(name), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r) && r != '.' && r != '-'
+	})
+	return strings.Join(fields, " ")
+}
+
+// AntivirusVendors returns the distinct, sorted vendors of the given raw
+// antivirus product names. Names not found in the catalog are skipped.
+func AntivirusVendors(names []string) []string {
+	seen := make(map[string]struct{})
+	var vendors []string
+	for _, name := range names {
+		vendor := NormalizeAntivirus(name).Vendor
+		if vendor == "" {
+			continue
+		}
+		if _, ok := seen[vendor]; ok {
+			continue
+		}
+		seen[vendor] = struct{}{}
+		vendors = append(vendors, vendor)
+	}
+	sort.Strings(vendors)
+	return vendors
+}
+
+// HasEDR reports whether any of the given antivirus product names is an EDR
+// product.
+func HasEDR(names []string) bool {
+	for _, name := range names {
+		if NormalizeAntivirus(name).ProductClass == AntivirusClassEDR {
+			return true
+		}
+	}
+	return false
+}
+
+// IsDefenderOnly reports whether the only protection found was Microsoft's
+// built-in Defender, i.e. the list is non-empty and every product resolves to
+// Microsoft consumer antivirus.
+func IsDefenderOnly(names []string) bool {
+	if len(names) == 0 {
+		return false
+	}
+	for _, name := range names {
+		product := NormalizeAntivirus(name)
+		if product.Vendor != "Microsoft" || product.ProductClass != AntivirusClassConsumerAV {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,122 @@
+package api
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestNormalizeAntivirus(t *testing.T) {
+	tests := []struct {
+		input        string
+		vendor       string
+		productClass string
+	}{
+		// Names of the same product
+		{"Windows Defender", "Microsoft", AntivirusClassConsumerAV},
+		{"Microsoft Defender Antivirus", "Microsoft", AntivirusClassConsumerAV},
+		{"Defender", "Microsoft", AntivirusClassConsumerAV},
+		{"  WINDOWS   DEFENDER ", "Microsoft", AntivirusClassConsumerAV},
+		{"Windows_Defender", "Microsoft", AntivirusClassConsumerAV},
+
+		// The longest pattern wins
+		{"Microsoft Defender for Endpoint", "Microsoft", AntivirusClassEDR},
+		{"Sophos Intercept X", "Sophos", AntivirusClassEDR},
+		{"Sophos Endpoint Agent", "Sophos", AntivirusClassEnterpriseEPP},
+		{"Sophos Home", "Sophos", AntivirusClassConsumerAV},
+		{"Kaspersky Endpoint Security for Windows", "Kaspersky", AntivirusClassEnterpriseEPP},
+		{"Kaspersky Internet Security", "Kaspersky", AntivirusClassConsumerAV},
+		{"McAfee Endpoint Security", "Trellix", AntivirusClassEnterpriseEPP},
+		{"McAfee LiveSafe", "McAfee", AntivirusClassConsumerAV},
+		{"Malwarebytes Endpoint Detection and Response", "Malwarebytes", AntivirusClassEDR},
+
+		// Patterns only match whole words
+		{"CrowdStrike Falcon Sensor", "CrowdStrike", AntivirusClassEDR},
+		{"Avast Free Antivirus", "Gen Digital", AntivirusClassConsumerAV},
+		{"AVG AntiVirus Free", "Gen Digital", AntivirusClassConsumerAV},
+		{"Avgust Scanner", "", AntivirusClassUnknown},
+		{"Dr.Web Security Space", "Doctor Web", AntivirusClassConsumerAV},
+		{"F-Secure SAFE", "F-Secure", AntivirusClassConsumerAV},
+		{"360 Total Security", "Qihoo 360", AntivirusClassConsumerAV},
+
+		// Unknown names
+		{"", "", AntivirusClassUnknown},
+		{"Acme Antivirus", "", AntivirusClassUnknown},
+	}
+	for _, tt := range tests {
+		want := AntivirusProduct{Name: tt.input, Vendor: tt.vendor, ProductClass: tt.productClass}
+		if got := NormalizeAntivirus(tt.input); got != want {
+			t.Errorf("NormalizeAntivirus(%q) = %+v, want %+v", tt.input, got, want)
+		}
+	}
+}
+
+func TestNormalizeAntivirusName(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string
+	}{
+		{"Windows Defender", "windows defender"},
+		{"  ESET  NOD32 (v16) ", "eset nod32 v16"},
+		{"Dr.Web", "dr.web"},
+		{"F-Secure", "f-secure"},
+		{"Norton™ 360", "norton 360"},
+		{"", ""},
+	}
+	for _, tt := range tests {
+		if got := normalizeAntivirusName(tt.input); got != tt.want {
+			t.Errorf("normalizeAntivirusName(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestAntivirusCatalog(t *testing.T) {
+	if AntivirusCatalogVersion() == "" {
+		t.Error("the catalog has no version")
+	}
+	classes := map[string]bool{AntivirusClassConsumerAV: true, AntivirusClassEnterpriseEPP: true, AntivirusClassEDR: true}
+	patterns := map[string]string{}
+	for _, entry := range defaultAntivirusCatalog.Products {
+		if entry.Vendor == "" || !classes[entry.ProductClass] {
+			t.Errorf("invalid catalog entry %+v", entry)
+		}
+		for _, pattern := range entry.Match {
+			// Patterns are matched against normalized names, so they must be
+			// normalized themselves
+			if normalizeAntivirusName(pattern) != pattern {
+				t.Errorf("pattern %q of %s is not normalized", pattern, entry.Vendor)
+			}
+			if vendor, ok := patterns[pattern]; ok {
+				t.Errorf("pattern %q is listed for both %s and %s", pattern, vendor, entry.Vendor)
+			}
+			patterns[pattern] = entry.Vendor
+		}
+	}
+}
+
+func TestAntivirusVendors(t *testing.T) {
+	tests := []struct {
+		names        []string
+		vendors      []string
+		hasEDR       bool
+		defenderOnly bool
+	}{
+		{names: nil},
+		{names: []string{"Windows Defender"}, vendors: []string{"Microsoft"}, defenderOnly: true},
+		{names: []string{"Windows Defender", "Microsoft Defender Antivirus"}, vendors: []string{"Microsoft"}, defenderOnly: true},
+		{names: []string{"Windows Defender", "Microsoft Defender for Endpoint"}, vendors: []string{"Microsoft"}, hasEDR: true},
+		{names: []string{"Windows Defender", "Acme Antivirus"}, vendors: []string{"Microsoft"}},
+		{names: []string{"Windows Defender", "CrowdStrike Falcon Sensor", "Avast Free Antivirus"}, vendors: []string{"CrowdStrike", "Gen Digital", "Microsoft"}, hasEDR: true},
+		{names: []string{"Acme Antivirus"}},
+	}
+	for _, tt := range tests {
+		if got := AntivirusVendors(tt.names); !reflect.DeepEqual(got, tt.vendors) {
+			t.Errorf("AntivirusVendors(%q) = %q, want %q", tt.names, got, tt.vendors)
+		}
+		if got := HasEDR(tt.names); got != tt.hasEDR {
+			t.Errorf("HasEDR(%q) = %t, want %t", tt.names, got, tt.hasEDR)
+		}
+		if got := IsDefenderOnly(tt.names); got != tt.defenderOnly {
+			t.Errorf("IsDefenderOnly(%q) = %t, want %t", tt.names, got, tt.defenderOnly)
+		}
+	}
+}
@@ -0,0 +1,114 @@
+package api
+
+import (
+	"fmt"
+	"math"
+	"math/rand"
+	"time"
+)
+
+// DefaultMaxDelay is the default cap of the delay between two attempts.
+const DefaultMaxDelay = 5 * time.Minute
+
+// Backoff strategy names, as set by backoff_strategy.
+const (
+	BackoffExponential        = "exponential"
+	BackoffFullJitter         = "full_jitter"
+	BackoffDecorrelatedJitter = "decorrelated_jitter"
+	BackoffConstant           = "constant"
+)
+
+// Backoff is what a BackoffStrategy computes the delay before a retry from.
+type Backoff struct {
+	// Attempt is the number of the attempt that failed, from 1
+	Attempt int
+	// Previous is the delay before Attempt, or 0 for the first attempt
+	Previous time.Duration
+	MinDelay time.Duration
+	// MaxDelay caps the delay; strategies may return more, as the client
+	// caps it again
+	MaxDelay time.Duration
+	// Rand is only used for the duration of the call
+	Rand *rand.Rand
+}
+
+// BackoffStrategy computes the delay before a request is retried.
+type BackoffStrategy interface {
+	Delay(b Backoff) time.Duration
+}
+
+// ExponentialBackoff multiplies MinDelay by Factor for every attempt, with
+// a jitter of ±20%. A zero Factor is 3.
+type ExponentialBackoff struct {
+	Factor float64
+}
+
+func (s ExponentialBackoff) Delay(b Backoff) time.Duration {
+	// The calculated jitter will be between [0.8, 1.2)
+	jitter := float64(b.Rand.Intn(120-80)+80) / 100
+	return exponentialDelay(b, s.Factor, 3, jitter)
+}
+
+// FullJitterBackoff waits a random delay between 0 and MinDelay multiplied
+// by Factor for every attempt, which spreads retries of concurrent queries
+// the most. A zero Factor is 2.
+type FullJitterBackoff struct {
+	Factor float64
+}
+
+func (s FullJitterBackoff) Delay(b Backoff) time.Duration {
+	return exponentialDelay(b, s.Factor, 2, b.Rand.Float64())
+}
+
+// DecorrelatedJitterBackoff waits a random delay between MinDelay and three
+// times the previous delay, so that delays grow without retries of
+// concurrent queries staying in step.
+type DecorrelatedJitterBackoff struct{}
+
+func (DecorrelatedJitterBackoff) Delay(b Backoff) time.Duration {
+	previous := math.Max(float64(b.Previous), float64(b.MinDelay))
+	delay := float64(b.MinDelay) + b.Rand.Float64()*(3*previous-float64(b.MinDelay))
+	return capDelay(delay, b.MaxDelay)
+}
+
+// ConstantBackoff always waits MinDelay.
+type ConstantBackoff struct{}
+
+func (ConstantBackoff) Delay(b Backoff) time.Duration {
+	return b.MinDelay
+}
+
+// NewBackoffStrategy returns the strategy with the given name, with its
+// default parameters.
+func NewBackoffStrategy(name string) (BackoffStrategy, error) {
+	switch name {
+	case BackoffExponential:
+		return ExponentialBackoff{}, nil
+	case BackoffFullJitter:
+		return FullJitterBackoff{}, nil
+	case BackoffDecorrelatedJitter:
+		return DecorrelatedJitterBackoff{}, nil
+	case BackoffConstant:
+		return ConstantBackoff{}, nil
+	}
+	return nil, fmt.Errorf("invalid backoff strategy %q, must be one of %s, %s, %s or %s", name, BackoffExponential, BackoffFullJitter, BackoffDecorrelatedJitter, BackoffConstant)
+}
+
+// exponentialDelay returns MinDelay * factor^Attempt * jitter, capped at
+// MaxDelay. It is computed in floating point, as MinDelay * 3^attempt
+// overflows int64 nanoseconds from the 28th attempt of a 100ms delay.
+func exponentialDelay(b Backoff, factor, defaultFactor, jitter float64) time.Duration {
+	if factor <= 0 {
+		factor = defaultFactor
+	}
+	return capDelay(float64(b.MinDelay)*math.Pow(factor, float64(b.Attempt))*jitter, b.MaxDelay)
+}
+
+// capDelay converts delay to a duration of at most maxDelay. Inf and NaN
+// fail the comparison and get the cap.
+func capDelay(delay float64, maxDelay time.Duration) time.Duration {
+	if delay < float64(maxDelay) {
+		return time.Duration(math.Max(delay, 0))
+	}
+	return maxDelay
+}
@@ -0,0 +1,98 @@
+package api
+
+import (
+	"math/rand"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+)
+
+func TestBackoffStrategies(t *testing.T) {
+	tests := []struct {
+		name     string
+		strategy BackoffStrategy
+		previous time.Duration
+		attempt  int
+		// The delay must be within [low, high)
+		low, high time.Duration
+	}{
+		{name: "exponential", strategy: ExponentialBackoff{}, attempt: 2, low: 7200 * time.Millisecond, high: 10800 * time.Millisecond},
+		{name: "exponential factor 2", strategy: ExponentialBackoff{Factor: 2}, attempt: 2, low: 3200 * time.Millisecond, high: 4800 * time.Millisecond},
+		{name: "full jitter", strategy: FullJitterBackoff{}, attempt: 3, low: 0, high: 8 * time.Second},
+		{name: "full jitter capped", strategy: FullJitterBackoff{}, attempt: 20, low: 0, high: time.Minute + 1},
+		{name: "decorrelated first", strategy: DecorrelatedJitterBackoff{}, attempt: 1, low: time.Second, high: 3 * time.Second},
+		{name: "decorrelated", strategy: DecorrelatedJitterBackoff{}, attempt: 4, previous: 10 * time.Second, low: time.Second, high: 30 * time.Second},
+		{name: "decorrelated capped", strategy: DecorrelatedJitterBackoff{}, attempt: 9, previous: time.Hour, low: time.Second, high: time.Minute + 1},
+		{name: "constant", strategy: ConstantBackoff{}, attempt: 7, low: time.Second, high: time.Second + 1},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient().WithRand(rand.New(rand.NewSource(1))).WithMinDelay(time.Second).WithMaxDelay(time.Minute).WithBackoffStrategy(tt.strategy)
+			for i := 0; i < 1000; i++ {
+				got, err := client.backoffDelay(tt.attempt, tt.previous, nil)
+				if err != nil {
+					t.Fatal(err)
+				}
+				if got < tt.low || got >= tt.high {
+					t.Fatalf("delay = %s, want within [%s, %s)", got, tt.low, tt.high)
+				}
+			}
+		})
+	}
+}
+
+func TestBackoffDelayCapsStrategies(t *testing.T) {
+	client := NewClient().WithMinDelay(time.Hour).WithMaxDelay(time.Minute).WithBackoffStrategy(ConstantBackoff{})
+	if got, _ := client.BackoffDelay(1, nil); got != time.Minute {
+		t.Errorf("BackoffDelay = %s, want the max delay", got)
+	}
+}
+
+// recordingBackoff waits one second more than the previous delay and
+// records what it was called with.
+type recordingBackoff struct {
+	calls []Backoff
+}
+
+func (s *recordingBackoff) Delay(b Backoff) time.Duration {
+	b.Rand = nil
+	s.calls = append(s.calls, b)
+	return b.Previous + time.Second
+}
+
+func TestBackoffStrategyGetsPreviousDelay(t *testing.T) {
+	client, server, clock := newRetryTestClient(t)
+	strategy := &recordingBackoff{}
+	client.WithMaxRetries(4).WithMaxDelay(time.Minute).WithBackoffStrategy(strategy)
+	server.Inject(hudsonrocktest.AnyEndpoint, hudsonrocktest.Repeat(3, hudsonrocktest.ServerError(http.StatusServiceUnavailable))...)
+
+	if _, err := client.SearchByIp(hudsonrocktest.Context(), "192.0.2.10"); err != nil {
+		t.Fatalf("SearchByIp: %v", err)
+	}
+
+	wantCalls := []Backoff{
+		{Attempt: 1, MinDelay: 100 * time.Millisecond, MaxDelay: time.Minute},
+		{Attempt: 2, Previous: time.Second, MinDelay: 100 * time.Millisecond, MaxDelay: time.Minute},
+		{Attempt: 3, Previous: 2 * time.Second, MinDelay: 100 * time.Millisecond, MaxDelay: time.Minute},
+	}
+	if !reflect.DeepEqual(strategy.calls, wantCalls) {
+		t.Errorf("strategy called with %+v, want %+v", strategy.calls, wantCalls)
+	}
+	if want := []time.Duration{time.Second, 2 * time.Second, 3 * time.Second}; !reflect.DeepEqual(clock.Sleeps(), want) {
+		t.Errorf("slept %v, want %v", clock.Sleeps(), want)
+	}
+}
+
+func TestNewBackoffStrategy(t *testing.T) {
+	for _, name := range []string{BackoffExponential, BackoffFullJitter, BackoffDecorrelatedJitter, BackoffConstant} {
+		if _, err := NewBackoffStrategy(name); err != nil {
+			t.Errorf("NewBackoffStrategy(%q): %v", name, err)
+		}
+	}
+	if _, err := NewBackoffStrategy("linear"); err == nil {
+		t.Error("NewBackoffStrategy(\"linear\") succeeded")
+	}
+}
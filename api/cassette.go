@@ -0,0 +1,282 @@
+package api
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Cassette modes.
+const (
+	CassetteRecord = "record"
+	CassetteReplay = "replay"
+)
+
+// ErrCassetteMiss is returned in replay mode when no cassette matches a
+// request. It is never retried.
+var ErrCassetteMiss = errors.New("no recorded response")
+
+// redacted replaces the values of sensitive headers and query parameters in
+// cassettes.
+const redacted = "REDACTED"
+
+// sensitiveHeaders are dropped from recorded responses.
+var sensitiveHeaders = map[string]bool{
+	"Authorization":       true,
+	"Cookie":              true,
+	"Proxy-Authorization": true,
+	"Set-Cookie":          true,
+	"X-Api-Key":           true,
+}
+
+// isSensitiveParam reports whether a query parameter may hold a credential.
+func isSensitiveParam(name string) bool {
+	name = strings.ToLower(name)
+	for _, s := range []string{"key", "token", "secret", "password", "auth"} {
+		if strings.Contains(name, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// lookupParams are the query parameters holding the email, IP address or
+// username looked up. Their values are redacted from recorded URLs.
+var lookupParams = map[string]bool{
+	"email":    true,
+	"ip":       true,
+	"username": true,
+}
+
+// victimFields are the fields of recorded email, IP and username responses
+// that identify a victim. Their values are replaced by pseudonyms, so that
+// replayed infections keep distinct fingerprints.
+var victimFields = map[string]bool{
+	"computer_name": true,
+	"ip":            true,
+	"malware_path":  true,
+	"top_logins":    true,
+	"top_passwords": true,
+}
+
+// Cassette is a recorded request and response pair.
+type Cassette struct {
+	RecordedAt time.Time        `json:"recorded_at"`
+	Request    CassetteRequest  `json:"request"`
+	Response   CassetteResponse `json:"response"`
+}
+
+type CassetteRequest struct {
+	Method string `json:"method"`
+	URL    string `json:"url"`
+}
+
+type CassetteResponse struct {
+	StatusCode int         `json:"status_code"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body"`
+}
+
+// CassetteTransport is an http.RoundTripper that records API traffic to
+// cassette files in a directory, or serves responses only from them.
+type CassetteTransport struct {
+	Mode string
+	Dir  string
+	// Base performs the requests in record mode.
+	Base http.RoundTripper
+	now  func() time.Time
+}
+
+// NewCassetteTransport returns a transport recording to or replaying from
+// dir. base is only used in record mode and defaults to
+// http.DefaultTransport. The directory is created on the first recording; in
+// replay mode a missing directory makes every request a cassette miss.
+func NewCassetteTransport(mode, dir string, base http.RoundTripper) (*CassetteTransport, error) {
+	if mode != CassetteRecord && mode != CassetteReplay {
+		return nil, fmt.Errorf("invalid cassette mode %q", mode)
+	}
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	return &CassetteTransport{Mode: mode, Dir: dir, Base: base, now: time.Now}, nil
+}
+
+func (t *CassetteTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	sanitized := sanitizeURL(req.URL)
+	file := filepath.Join(t.Dir, cassetteName(req.Method, sanitized))
+
+	if t.Mode == CassetteReplay {
+		return t.replay(req, sanitized, file)
+	}
+
+	resp, err := t.Base.RoundTrip(req)
+	if err != nil {
+		return nil, err
+	}
+	body, err := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+
+	header := http.Header{}
+	for name, values := range resp.Header {
+		if !sensitiveHeaders[http.CanonicalHeaderKey(name)] {
+			header[name] = values
+		}
+	}
+	recorded := body
+	if isLookup(req.URL) {
+		// Bodies that are not JSON, such as proxy error pages, hold no
+		// stealer data and are recorded as is
+		if redactedBody, err := redactVictimFields(body); err == nil {
+			recorded = redactedBody
+		}
+	}
+	cassette := Cassette{
+		RecordedAt: t.now().UTC(),
+		Request:    CassetteRequest{Method: req.Method, URL: redactURL(req.URL, isRecordedParamSensitive)},
+		Response:   CassetteResponse{StatusCode: resp.StatusCode, Header: header, Body: string(recorded)},
+	}
+	data, err := json.MarshalIndent(cassette, "", "  ")
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(t.Dir, 0o700); err != nil {
+		return nil, fmt.Errorf("creating record directory: %w", err)
+	}
+	if err := os.WriteFile(file, data, 0o600); err != nil {
+		return nil, fmt.Errorf("recording cassette: %w", err)
+	}
+	return resp, nil
+}
+
+func (t *CassetteTransport) replay(req *http.Request, sanitized, file string) (*http.Response, error) {
+	data, err := os.ReadFile(file)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, fmt.Errorf("replay: %w for %s %s (expected %s)", ErrCassetteMiss, req.Method, sanitized, file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("replay: %w", err)
+	}
+
+	var cassette Cassette
+	if err := json.Unmarshal(data, &cassette); err != nil {
+		return nil, fmt.Errorf("replay: invalid cassette %s: %w", file, err)
+	}
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", cassette.Response.StatusCode, http.StatusText(cassette.Response.StatusCode)),
+		StatusCode:    cassette.Response.StatusCode,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        cassette.Response.Header,
+		Body:          io.NopCloser(strings.NewReader(cassette.Response.Body)),
+		ContentLength: int64(len(cassette.Response.Body)),
+		Request:       req,
+	}, nil
+}
+
+// sanitizeURL returns the request path and sorted query with the values of
+// sensitive parameters redacted. The host is left out so that cassettes
+// recorded against one base URL replay against another.
+func sanitizeURL(u *url.URL) string {
+	return redactURL(u, isSensitiveParam)
+}
+
+// isRecordedParamSensitive reports whether the value of a query parameter is
+// left out of recorded URLs: credentials and looked up values. The file name
+// of a cassette still depends on the looked up value, through a hash.
+func isRecordedParamSensitive(name string) bool {
+	return isSensitiveParam(name) || lookupParams[name]
+}
+
+// isLookup reports whether u is an email, IP or username lookup.
+func isLookup(u *url.URL) bool {
+	for name := range u.Query() {
+		if lookupParams[name] {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL returns the request path and sorted query with the values of the
+// parameters matching sensitive redacted.
+func redactURL(u *url.URL, sensitive func(name string) bool) string {
+	query := u.Query()
+	if len(query) == 0 {
+		return u.Path
+	}
+	for key, values := range query {
+		if sensitive(key) {
+			for i := range values {
+				values[i] = redacted
+			}
+		}
+	}
+	// Encode sorts the parameters by key.
+	return u.Path + "?" + query.Encode()
+}
+
+// redactVictimFields replaces the victim fields of a JSON body, at any depth,
+// with pseudonyms. It fails if the body is not JSON.
+func redactVictimFields(body []byte) ([]byte, error) {
+	decoder := json.NewDecoder(bytes.NewReader(body))
+	// Keep numbers as they were returned
+	decoder.UseNumber()
+	var value any
+	if err := decoder.Decode(&value); err != nil {
+		return nil, err
+	}
+	return json.Marshal(redactVictimValue(value, false))
+}
+
+func redactVictimValue(value any, victim bool) any {
+	switch v := value.(type) {
+	case map[string]any:
+		for key, field := range v {
+			v[key] = redactVictimValue(field, victimFields[key])
+		}
+	case []any:
+		for i, item := range v {
+			v[i] = redactVictimValue(item, victim)
+		}
+	case string:
+		if victim && v != "" {
+			return pseudonym(v)
+		}
+	}
+	return value
+}
+
+// pseudonym returns a stand-in for a redacted value, the same for equal
+// values. It is a truncated hash, so guessable values such as IP addresses
+// can still be recovered by brute force.
+func pseudonym(value string) string {
+	sum := sha256.Sum256([]byte(value))
+	return redacted + "-" + hex.EncodeToString(sum[:4])
+}
+
+// cassetteName returns the file name of the cassette for a request: the
+// endpoint name followed by a hash of the method and sanitized URL.
+func cassetteName(method, sanitizedURL string) string {
+	sum := sha256.Sum256([]byte(method + " " + sanitizedURL))
+	name := path.Base(strings.SplitN(sanitizedURL, "?", 2)[0])
+	if name == "/" || name == "." {
+		name = "root"
+	}
+	return name + "-" + hex.EncodeToString(sum[:8]) + ".json"
+}
@@ -0,0 +1,158 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+)
+
+func TestCassetteRecordAndReplay(t *testing.T) {
+	dir := t.TempDir()
+	ctx := hudsonrocktest.Context()
+
+	client, server := newTestClient(t)
+	if _, err := client.WithCassettes(CassetteRecord, dir); err != nil {
+		t.Fatalf("WithCassettes: %v", err)
+	}
+	recorded, err := client.SearchByUsername(ctx, "jdoe")
+	if err != nil {
+		t.Fatalf("SearchByUsername while recording: %v", err)
+	}
+	server.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "search-by-username-*.json"))
+	if len(files) != 1 {
+		t.Fatalf("got %d cassettes, want 1", len(files))
+	}
+
+	replay := NewClient().WithBaseURL("http://127.0.0.1:1").WithMinDelay(time.Millisecond)
+	if _, err := replay.WithCassettes(CassetteReplay, dir); err != nil {
+		t.Fatalf("WithCassettes: %v", err)
+	}
+	replayed, err := replay.SearchByUsername(ctx, "jdoe")
+	if err != nil {
+		t.Fatalf("SearchByUsername while replaying: %v", err)
+	}
+	if len(replayed.Stealers) != len(recorded.Stealers) || replayed.Stealers[0].DateCompromised != recorded.Stealers[0].DateCompromised {
+		t.Errorf("replayed %+v, recorded %+v", replayed, recorded)
+	}
+
+	_, err = replay.SearchByUsername(ctx, "someone-else")
+	if !errors.Is(err, ErrCassetteMiss) {
+		t.Fatalf("got error %v, want ErrCassetteMiss", err)
+	}
+	if !strings.Contains(err.Error(), "username=someone-else") {
+		t.Errorf("miss error %q does not name the request", err)
+	}
+}
+
+func TestCassetteSanitizesCredentials(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		http.SetCookie(w, &http.Cookie{Name: "session", Value: "s3cr3t"})
+		w.Header().Set("Content-Type", "application/json")
+		_, _ = w.Write([]byte(`{"message":"ok"}`))
+	}))
+	defer server.Close()
+
+	dir := t.TempDir()
+	transport, err := NewCassetteTransport(CassetteRecord, dir, nil)
+	if err != nil {
+		t.Fatalf("NewCassetteTransport: %v", err)
+	}
+	resp, err := (&http.Client{Transport: transport}).Get(server.URL + "/api/json/v2/osint-tools/search-by-email?email=jane%40example.com&api_key=hunter2")
+	if err != nil {
+		t.Fatalf("GET: %v", err)
+	}
+	resp.Body.Close()
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("got %d cassettes, want 1", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"hunter2", "s3cr3t", "Set-Cookie", "127.0.0.1"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "api_key="+redacted) {
+		t.Errorf("cassette does not contain the redacted api_key:\n%s", data)
+	}
+}
+
+func TestCassetteRedactsVictimData(t *testing.T) {
+	dir := t.TempDir()
+	ctx := hudsonrocktest.Context()
+
+	client, server := newTestClient(t)
+	if _, err := client.WithCassettes(CassetteRecord, dir); err != nil {
+		t.Fatalf("WithCassettes: %v", err)
+	}
+	recorded, err := client.SearchByEmail(ctx, "jane.doe@example.com")
+	if err != nil {
+		t.Fatalf("SearchByEmail while recording: %v", err)
+	}
+	server.Close()
+	// The live response is left untouched
+	if recorded.Stealers[0].ComputerName != "DESKTOP-7H2KQ1" {
+		t.Errorf("recorded computer name = %q", recorded.Stealers[0].ComputerName)
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 1 {
+		t.Fatalf("got %d cassettes, want 1", len(files))
+	}
+	data, err := os.ReadFile(files[0])
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, secret := range []string{"jane.doe", "DESKTOP-7H2KQ1", "192.0.2.10", "setup_x64.exe", "S********1"} {
+		if strings.Contains(string(data), secret) {
+			t.Errorf("cassette contains %q:\n%s", secret, data)
+		}
+	}
+	if !strings.Contains(string(data), "email="+redacted) {
+		t.Errorf("cassette does not contain the redacted email:\n%s", data)
+	}
+
+	replay := NewClient().WithBaseURL("http://127.0.0.1:1").WithMinDelay(time.Millisecond)
+	if _, err := replay.WithCassettes(CassetteReplay, dir); err != nil {
+		t.Fatalf("WithCassettes: %v", err)
+	}
+	replayed, err := replay.SearchByEmail(ctx, "jane.doe@example.com")
+	if err != nil {
+		t.Fatalf("SearchByEmail while replaying: %v", err)
+	}
+	if len(replayed.Stealers) != 2 {
+		t.Fatalf("replayed %d stealers, want 2", len(replayed.Stealers))
+	}
+	first, second := replayed.Stealers[0], replayed.Stealers[1]
+	if !strings.HasPrefix(first.ComputerName, redacted+"-") || first.OperatingSystem != recorded.Stealers[0].OperatingSystem {
+		t.Errorf("replayed stealer = %+v", first)
+	}
+	if first.InfectionID() == second.InfectionID() {
+		t.Errorf("replayed stealers share the fingerprint %s", first.InfectionID())
+	}
+	if _, err := replay.SearchByEmail(ctx, "someone@example.com"); !errors.Is(err, ErrCassetteMiss) {
+		t.Errorf("got error %v for another email, want ErrCassetteMiss", err)
+	}
+}
+
+func TestSanitizeURLIsOrderIndependent(t *testing.T) {
+	a, _ := url.Parse("https://example.com/api?b=2&a=1&token=x")
+	b, _ := url.Parse("http://localhost:8089/api?token=y&a=1&b=2")
+	if sanitizeURL(a) != sanitizeURL(b) {
+		t.Errorf("sanitizeURL(%s) = %q, sanitizeURL(%s) = %q", a, sanitizeURL(a), b, sanitizeURL(b))
+	}
+}
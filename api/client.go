@@ -1,10 +1,13 @@
 package api
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"log"
-	"math"
 	"math/rand"
+	"strings"
+	"sync"
 	"time"
 
 	"resty.dev/v3"
@@ -12,13 +15,30 @@ import (
 
 const BaseURL = "https://cavalier.hudsonrock.com"
 
+// DefaultRequestTimeout is the default timeout of a single request attempt.
+const DefaultRequestTimeout = 30 * time.Second
+
 // Client is a reusable HTTP client for the Hudson Rock API using Resty.
 type Client struct {
 	Resty      *resty.Client
 	BaseURL    string
 	MaxRetries int
 	MinDelay   time.Duration
-	rand       *rand.Rand
+	// MaxDelay caps the delay between two attempts
+	MaxDelay time.Duration
+	Backoff  BackoffStrategy
+	// StrictDecoding is one of the StrictDecoding* modes
+	StrictDecoding string
+	// MaxResponseBytes is the number of bytes of a domain response after
+	// which URLs are discarded, or 0 for no limit
+	MaxResponseBytes int64
+	limiter          RateLimiter
+	clock            Clock
+
+	// rand is shared by the goroutines of fan-out tables, and *rand.Rand is
+	// not safe for concurrent use.
+	randMu sync.Mutex
+	rand   *rand.Rand
 }
 
 // NewClient returns a new Client with a Resty client and the Hudson Rock API base URL.
@@ -26,22 +46,64 @@ func NewClient() *Client {
 	client := resty.New()
 
 	// Configure timeouts
-	client.SetTimeout(30 * time.Second)
+	client.SetTimeout(DefaultRequestTimeout)
 
-	// Basic retry configuration if available
-	if err := client.SetRetryCount(3); err != nil {
-		log.Printf("[WARNING] Could not set retry count: %v", err)
-	}
+	// Retries are made by executeWithRetry, so that every attempt waits on
+	// the rate limiter and counts towards MaxRetries
+	client.SetRetryCount(0)
 
 	return &Client{
-		Resty:      client,
-		BaseURL:    BaseURL,
-		MaxRetries: 3,                                               // Default to 3 retries
-		MinDelay:   100 * time.Millisecond,                          // Default minimum delay
-		rand:       rand.New(rand.NewSource(time.Now().UnixNano())), // Modern random source
+		Resty:            client,
+		BaseURL:          BaseURL,
+		MaxRetries:       3,                                               // Default to 3 retries
+		MinDelay:         100 * time.Millisecond,                          // Default minimum delay
+		MaxDelay:         DefaultMaxDelay,                                 // Default maximum delay
+		Backoff:          ExponentialBackoff{},                            // Base 3 with ±20% jitter
+		StrictDecoding:   StrictDecodingOff,                               // Ignore unknown fields
+		MaxResponseBytes: DefaultMaxResponseBytes,                         // Keep at most 100 MiB of URLs
+		clock:            realClock{},                                     // Wall clock
+		rand:             rand.New(rand.NewSource(time.Now().UnixNano())), // Modern random source
 	}
 }
 
+// WithClock sets the clock used to wait between attempts
+func (c *Client) WithClock(clock Clock) *Client {
+	c.clock = clock
+	return c
+}
+
+// WithRand sets the random source used for backoff jitter
+func (c *Client) WithRand(r *rand.Rand) *Client {
+	c.randMu.Lock()
+	defer c.randMu.Unlock()
+	c.rand = r
+	return c
+}
+
+// WithBaseURL sets the base URL of the API, e.g. to use a mock server
+func (c *Client) WithBaseURL(baseURL string) *Client {
+	c.BaseURL = strings.TrimSuffix(baseURL, "/")
+	return c
+}
+
+// WithCassettes records every request and response to cassette files in
+// dir, or serves responses only from them, depending on mode.
+func (c *Client) WithCassettes(mode, dir string) (*Client, error) {
+	transport, err := NewCassetteTransport(mode, dir, c.Resty.Transport())
+	if err != nil {
+		return c, err
+	}
+	c.Resty.SetTransport(transport)
+	return c, nil
+}
+
+// WithRequestTimeout sets the timeout of a single request attempt, including
+// reading the response
+func (c *Client) WithRequestTimeout(timeout time.Duration) *Client {
+	c.Resty.SetTimeout(timeout)
+	return c
+}
+
 // WithMaxRetries sets the maximum number of retries for the client
 func (c *Client) WithMaxRetries(maxRetries int) *Client {
 	c.MaxRetries = maxRetries
@@ -54,21 +116,66 @@ func (c *Client) WithMinDelay(minDelay time.Duration) *Client {
 	return c
 }
 
+// WithMaxResponseBytes sets the number of bytes of a domain response after
+// which URLs are discarded. 0 disables the limit.
+func (c *Client) WithMaxResponseBytes(maxBytes int64) *Client {
+	c.MaxResponseBytes = maxBytes
+	return c
+}
+
+// WithMaxDelay sets the cap of the delay between two attempts
+func (c *Client) WithMaxDelay(maxDelay time.Duration) *Client {
+	c.MaxDelay = maxDelay
+	return c
+}
+
+// WithBackoffStrategy sets the strategy computing the delay before a retry
+func (c *Client) WithBackoffStrategy(strategy BackoffStrategy) *Client {
+	c.Backoff = strategy
+	return c
+}
+
+// RateLimiter throttles the requests of a client, e.g. a *rate.Limiter.
+type RateLimiter interface {
+	Wait(ctx context.Context) error
+}
+
+// WithRateLimiter sets a limiter that every request attempt waits on. The
+// limiter may be shared by several clients to throttle them together.
+func (c *Client) WithRateLimiter(limiter RateLimiter) *Client {
+	c.limiter = limiter
+	return c
+}
+
 // BackoffDelay returns the duration to wait before the next attempt should be
 // made. Returns an error if unable get a duration.
 func (c *Client) BackoffDelay(attempt int, err error) (time.Duration, error) {
-	minDelay := c.MinDelay
-
-	// The calculated jitter will be between [0.8, 1.2)
-	var jitter = float64(c.rand.Intn(120-80)+80) / 100
-
-	retryTime := time.Duration(int(float64(int(minDelay.Nanoseconds())*int(math.Pow(3, float64(attempt)))) * jitter))
+	return c.backoffDelay(attempt, 0, err)
+}
 
-	// Cap retry time at 5 minutes to avoid too long a wait
-	if retryTime > time.Duration(5*time.Minute) {
-		retryTime = time.Duration(5 * time.Minute)
+// backoffDelay returns the delay of the client's backoff strategy before the
+// attempt after attempt, whose own delay was previous.
+func (c *Client) backoffDelay(attempt int, previous time.Duration, err error) (time.Duration, error) {
+	maxDelay := c.MaxDelay
+	if maxDelay <= 0 {
+		maxDelay = DefaultMaxDelay
+	}
+	strategy := c.Backoff
+	if strategy == nil {
+		strategy = ExponentialBackoff{}
 	}
 
+	c.randMu.Lock()
+	retryTime := strategy.Delay(Backoff{
+		Attempt:  attempt,
+		Previous: previous,
+		MinDelay: c.MinDelay,
+		MaxDelay: maxDelay,
+		Rand:     c.rand,
+	})
+	c.randMu.Unlock()
+	retryTime = min(max(retryTime, 0), maxDelay)
+
 	// Low level method to log retries since we don't have context etc here.
 	// Logging is helpful for visibility into retries and choke points in using
 	// the API.
@@ -78,15 +185,34 @@ func (c *Client) BackoffDelay(attempt int, err error) (time.Duration, error) {
 }
 
 // executeWithRetry performs an HTTP request with manual retry logic
-func (c *Client) executeWithRetry(request func() (*resty.Response, error), maxRetries int) (*resty.Response, error) {
+func (c *Client) executeWithRetry(ctx context.Context, request func() (*resty.Response, error), maxRetries int) (*resty.Response, error) {
 	var lastErr error
 	var resp *resty.Response
+	var backoff time.Duration
+	start := c.clock.Now()
+
+	// Always make at least one attempt
+	if maxRetries < 1 {
+		maxRetries = 1
+	}
 
 	for attempt := 1; attempt <= maxRetries; attempt++ {
+		if c.limiter != nil {
+			if err := c.limiter.Wait(ctx); err != nil {
+				return resp, fmt.Errorf("rate limiter: %w", err)
+			}
+		}
+
 		log.Printf("[REQUEST] Attempt %d/%d", attempt, maxRetries)
 
 		resp, lastErr = request()
 
+		// A missing cassette will still be missing on the next attempt, and
+		// the same response will drift the same way
+		if errors.Is(lastErr, ErrCassetteMiss) || errors.Is(lastErr, ErrSchemaDrift) {
+			return resp, lastErr
+		}
+
 		if lastErr == nil && resp != nil {
 			statusCode := resp.StatusCode()
 			log.Printf("[RESPONSE] Status: %d", statusCode)
@@ -124,24 +250,28 @@ func (c *Client) executeWithRetry(request func() (*resty.Response, error), maxRe
 
 		// Don't sleep after the last attempt
 		if attempt < maxRetries {
-			backoff, err := c.BackoffDelay(attempt, lastErr)
+			var err error
+			backoff, err = c.backoffDelay(attempt, backoff, lastErr)
 			if err != nil {
 				log.Printf("[ERROR] Failed to calculate backoff delay: %v", err)
 				backoff = 1 * time.Second // fallback
 			}
 
-			time.Sleep(backoff)
+			if err := c.clock.Sleep(ctx, backoff); err != nil {
+				return resp, fmt.Errorf("waiting to retry: %w", err)
+			}
 		}
 	}
 
+	elapsed := c.clock.Now().Sub(start).Round(time.Millisecond)
 	if lastErr != nil {
-		return resp, fmt.Errorf("request failed after %d attempts: %w", maxRetries, lastErr)
+		return resp, fmt.Errorf("request failed after %d attempts in %s: %w", maxRetries, elapsed, lastErr)
 	}
 
-	return resp, fmt.Errorf("request failed after %d attempts with status %d", maxRetries, resp.StatusCode())
+	return resp, fmt.Errorf("request failed after %d attempts in %s with status %d", maxRetries, elapsed, resp.StatusCode())
 }
 
 // executeWithRetryDefault performs an HTTP request using the client's default retry settings
-func (c *Client) executeWithRetryDefault(request func() (*resty.Response, error)) (*resty.Response, error) {
-	return c.executeWithRetry(request, c.MaxRetries)
+func (c *Client) executeWithRetryDefault(ctx context.Context, request func() (*resty.Response, error)) (*resty.Response, error) {
+	return c.executeWithRetry(ctx, request, c.MaxRetries)
 }
@@ -0,0 +1,232 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+)
+
+// fakeClock records the delays the client sleeps for without waiting.
+type fakeClock struct {
+	mu     sync.Mutex
+	now    time.Time
+	sleeps []time.Duration
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.sleeps = append(c.sleeps, d)
+	c.now = c.now.Add(d)
+	return ctx.Err()
+}
+
+func (c *fakeClock) Sleeps() []time.Duration {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]time.Duration(nil), c.sleeps...)
+}
+
+// constSource makes rand.Intn always return 0, so the jitter is 0.8.
+type constSource struct{}
+
+func (constSource) Int63() int64 { return 0 }
+func (constSource) Seed(int64)   {}
+
+func newRetryTestClient(t *testing.T) (*Client, *hudsonrocktest.Server, *fakeClock) {
+	t.Helper()
+	client, server := newTestClient(t)
+	clock := &fakeClock{}
+	client.WithClock(clock).WithRand(rand.New(constSource{})).WithMinDelay(100 * time.Millisecond)
+	return client, server, clock
+}
+
+func TestExecuteWithRetryStatusCodes(t *testing.T) {
+	tests := []struct {
+		status       int
+		retried      bool
+		wantErrMatch string
+	}{
+		{status: http.StatusRequestTimeout, retried: true},
+		{status: http.StatusTooManyRequests, retried: true},
+		{status: http.StatusInternalServerError, retried: true},
+		{status: http.StatusBadGateway, retried: true},
+		{status: http.StatusServiceUnavailable, retried: true},
+		{status: http.StatusGatewayTimeout, retried: true},
+		{status: http.StatusBadRequest, wantErrMatch: "HTTP client error: 400"},
+		{status: http.StatusUnauthorized, wantErrMatch: "HTTP client error: 401"},
+		{status: http.StatusForbidden, wantErrMatch: "HTTP client error: 403"},
+		{status: http.StatusNotFound, wantErrMatch: "HTTP client error: 404"},
+		{status: http.StatusUnprocessableEntity, wantErrMatch: "HTTP client error: 422"},
+		{status: http.StatusNotImplemented, wantErrMatch: "HTTP error: 501"},
+		{status: http.StatusHTTPVersionNotSupported, wantErrMatch: "HTTP error: 505"},
+		{status: http.StatusNotModified, wantErrMatch: "HTTP error: 304"},
+	}
+	for _, tt := range tests {
+		t.Run(http.StatusText(tt.status), func(t *testing.T) {
+			// A persistent failure
+			client, server, clock := newRetryTestClient(t)
+			server.Inject(hudsonrocktest.AnyEndpoint, hudsonrocktest.Repeat(3, hudsonrocktest.ServerError(tt.status))...)
+
+			_, err := client.SearchByIp(hudsonrocktest.Context(), "192.0.2.10")
+			if err == nil {
+				t.Fatal("expected an error")
+			}
+			wantRequests, wantSleeps := 1, 0
+			if tt.retried {
+				wantRequests, wantSleeps = 3, 2
+				if !strings.Contains(err.Error(), "request failed after 3 attempts") {
+					t.Errorf("error %q does not report the attempts", err)
+				}
+			} else if !strings.Contains(err.Error(), tt.wantErrMatch) {
+				t.Errorf("error %q does not contain %q", err, tt.wantErrMatch)
+			}
+			if got := server.RequestCount(hudsonrocktest.AnyEndpoint); got != wantRequests {
+				t.Errorf("got %d requests, want %d", got, wantRequests)
+			}
+			if got := len(clock.Sleeps()); got != wantSleeps {
+				t.Errorf("slept %d times, want %d", got, wantSleeps)
+			}
+
+			// A single failure
+			if !tt.retried {
+				return
+			}
+			client, server, _ = newRetryTestClient(t)
+			server.Inject(hudsonrocktest.AnyEndpoint, hudsonrocktest.ServerError(tt.status))
+			if _, err := client.SearchByIp(hudsonrocktest.Context(), "192.0.2.10"); err != nil {
+				t.Errorf("expected the retry to succeed, got %v", err)
+			}
+			if got := server.RequestCount(hudsonrocktest.AnyEndpoint); got != 2 {
+				t.Errorf("got %d requests, want 2", got)
+			}
+		})
+	}
+}
+
+func TestExecuteWithRetryAttemptCounts(t *testing.T) {
+	tests := []struct {
+		maxRetries   int
+		wantRequests int
+		wantSleeps   []time.Duration
+	}{
+		{maxRetries: 0, wantRequests: 1},
+		{maxRetries: 1, wantRequests: 1},
+		{maxRetries: 2, wantRequests: 2, wantSleeps: []time.Duration{240 * time.Millisecond}},
+		{maxRetries: 4, wantRequests: 4, wantSleeps: []time.Duration{240 * time.Millisecond, 720 * time.Millisecond, 2160 * time.Millisecond}},
+	}
+	for _, tt := range tests {
+		client, server, clock := newRetryTestClient(t)
+		client.WithMaxRetries(tt.maxRetries)
+		server.Inject(hudsonrocktest.AnyEndpoint, hudsonrocktest.Repeat(10, hudsonrocktest.ServerError(http.StatusServiceUnavailable))...)
+
+		if _, err := client.SearchByIp(hudsonrocktest.Context(), "192.0.2.10"); err == nil {
+			t.Errorf("max_retries %d: expected an error", tt.maxRetries)
+		}
+		if got := server.RequestCount(hudsonrocktest.AnyEndpoint); got != tt.wantRequests {
+			t.Errorf("max_retries %d: got %d requests, want %d", tt.maxRetries, got, tt.wantRequests)
+		}
+		sleeps := clock.Sleeps()
+		if len(sleeps) != len(tt.wantSleeps) {
+			t.Fatalf("max_retries %d: got sleeps %v, want %v", tt.maxRetries, sleeps, tt.wantSleeps)
+		}
+		for i := range sleeps {
+			if sleeps[i] != tt.wantSleeps[i] {
+				t.Errorf("max_retries %d: sleep %d = %s, want %s", tt.maxRetries, i, sleeps[i], tt.wantSleeps[i])
+			}
+		}
+	}
+}
+
+func TestExecuteWithRetryNetworkError(t *testing.T) {
+	client, server, clock := newRetryTestClient(t)
+	server.Close()
+
+	_, err := client.SearchByIp(hudsonrocktest.Context(), "192.0.2.10")
+	if err == nil || !strings.Contains(err.Error(), "request failed after 3 attempts") {
+		t.Fatalf("got error %v, want a failure after 3 attempts", err)
+	}
+	if got := len(clock.Sleeps()); got != 2 {
+		t.Errorf("slept %d times, want 2", got)
+	}
+}
+
+func TestExecuteWithRetryCancelledWhileWaiting(t *testing.T) {
+	client, server, clock := newRetryTestClient(t)
+	server.Inject(hudsonrocktest.AnyEndpoint, hudsonrocktest.Repeat(3, hudsonrocktest.ServerError(http.StatusServiceUnavailable))...)
+
+	ctx, cancel := context.WithCancel(hudsonrocktest.Context())
+	client.WithClock(cancelingClock{clock, cancel})
+
+	_, err := client.SearchByIp(ctx, "192.0.2.10")
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("got error %v, want context.Canceled", err)
+	}
+	if got := server.RequestCount(hudsonrocktest.AnyEndpoint); got != 1 {
+		t.Errorf("got %d requests, want 1", got)
+	}
+}
+
+// cancelingClock cancels the request context when the client starts waiting.
+type cancelingClock struct {
+	*fakeClock
+	cancel context.CancelFunc
+}
+
+func (c cancelingClock) Sleep(ctx context.Context, d time.Duration) error {
+	c.cancel()
+	return c.fakeClock.Sleep(ctx, d)
+}
+
+func TestBackoffDelay(t *testing.T) {
+	tests := []struct {
+		name     string
+		minDelay time.Duration
+		attempt  int
+		want     time.Duration
+	}{
+		{name: "first retry", minDelay: 100 * time.Millisecond, attempt: 1, want: 240 * time.Millisecond},
+		{name: "second retry", minDelay: 100 * time.Millisecond, attempt: 2, want: 720 * time.Millisecond},
+		{name: "one second base", minDelay: time.Second, attempt: 3, want: 21600 * time.Millisecond},
+		{name: "zero delay", minDelay: 0, attempt: 5, want: 0},
+		{name: "just under the cap", minDelay: 100 * time.Millisecond, attempt: 7, want: 174960 * time.Millisecond},
+		{name: "capped", minDelay: 100 * time.Millisecond, attempt: 8, want: DefaultMaxDelay},
+		{name: "int64 overflow", minDelay: 100 * time.Millisecond, attempt: 40, want: DefaultMaxDelay},
+		{name: "float overflow", minDelay: time.Hour, attempt: 1000, want: DefaultMaxDelay},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client := NewClient().WithRand(rand.New(constSource{})).WithMinDelay(tt.minDelay)
+			got, err := client.BackoffDelay(tt.attempt, nil)
+			if err != nil {
+				t.Fatalf("BackoffDelay: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("BackoffDelay(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestBackoffDelayJitterRange(t *testing.T) {
+	client := NewClient().WithRand(rand.New(rand.NewSource(1))).WithMinDelay(time.Second)
+	for i := 0; i < 1000; i++ {
+		got, _ := client.BackoffDelay(1, nil)
+		if got < 2400*time.Millisecond || got >= 3600*time.Millisecond {
+			t.Fatalf("BackoffDelay(1) = %s, want within [2.4s, 3.6s)", got)
+		}
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+)
+
+func newTestClient(t *testing.T) (*Client, *hudsonrocktest.Server) {
+	t.Helper()
+	server := hudsonrocktest.NewServer()
+	t.Cleanup(server.Close)
+	client := NewClient().WithBaseURL(server.URL).WithClock(&fakeClock{})
+	return client, server
+}
+
+func TestClientEndpoints(t *testing.T) {
+	client, server := newTestClient(t)
+	ctx := hudsonrocktest.Context()
+
+	email, err := client.SearchByEmail(ctx, "jane.doe@example.com")
+	if err != nil {
+		t.Fatalf("SearchByEmail: %v", err)
+	}
+	if len(email.Stealers) != 2 || email.Stealers[0].ComputerName != "DESKTOP-7H2KQ1" {
+		t.Errorf("SearchByEmail: unexpected stealers %+v", email.Stealers)
+	}
+
+	ip, err := client.SearchByIp(ctx, "192.0.2.10")
+	if err != nil {
+		t.Fatalf("SearchByIp: %v", err)
+	}
+	if len(ip.Stealers) != 1 || ip.Stealers[0].IP != "192.0.2.10" {
+		t.Errorf("SearchByIp: unexpected stealers %+v", ip.Stealers)
+	}
+
+	username, err := client.SearchByUsername(ctx, "jdoe")
+	if err != nil {
+		t.Fatalf("SearchByUsername: %v", err)
+	}
+	if len(username.Stealers) != 2 || username.Stealers[0].StealerFamily != "Lumma" {
+		t.Errorf("SearchByUsername: unexpected stealers %+v", username.Stealers)
+	}
+
+	domain, err := client.SearchByDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("SearchByDomain: %v", err)
+	}
+	if domain.Employees != 37 || domain.Users != 1247 || domain.StealerFamilies["Lumma"] != 611 || !domain.EmployeePasswords.HasStats {
+		t.Errorf("SearchByDomain: unexpected result %+v", domain)
+	}
+
+	urls, err := client.UrlByDomain(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("UrlByDomain: %v", err)
+	}
+	if len(urls.Data.EmployeesURLs) != 2 || urls.Data.ClientsURLs[0].URL != "https://shop.example.com/login" {
+		t.Errorf("UrlByDomain: unexpected data %+v", urls.Data)
+	}
+
+	requests := server.Requests()
+	if len(requests) != 5 {
+		t.Fatalf("got %d requests, want 5", len(requests))
+	}
+	if got := requests[0].Query.Get("email"); got != "jane.doe@example.com" {
+		t.Errorf("email query = %q", got)
+	}
+}
+
+func TestClientResponseOverride(t *testing.T) {
+	client, server := newTestClient(t)
+	server.SetResponse(hudsonrocktest.EndpointSearchByEmail, "clean@example.com",
+		[]byte(`{"message":"This email address is not associated with a computer infected by an info-stealer.","stealers":[],"total_corporate_services":0,"total_user_services":0}`))
+
+	result, err := client.SearchByEmail(hudsonrocktest.Context(), "Clean@example.com")
+	if err != nil {
+		t.Fatalf("SearchByEmail: %v", err)
+	}
+	if len(result.Stealers) != 0 {
+		t.Errorf("got %d stealers, want 0", len(result.Stealers))
+	}
+}
+
+func TestClientFaults(t *testing.T) {
+	tests := []struct {
+		name         string
+		faults       []hudsonrocktest.Fault
+		wantErr      bool
+		wantRequests int
+	}{
+		{name: "server error burst", faults: hudsonrocktest.Repeat(2, hudsonrocktest.ServerError(http.StatusServiceUnavailable)), wantRequests: 3},
+		{name: "server error exhausts retries", faults: hudsonrocktest.Repeat(3, hudsonrocktest.ServerError(http.StatusBadGateway)), wantErr: true, wantRequests: 3},
+		{name: "rate limited", faults: []hudsonrocktest.Fault{hudsonrocktest.RateLimited(time.Second)}, wantRequests: 2},
+		{name: "slow response", faults: []hudsonrocktest.Fault{hudsonrocktest.Slow(50 * time.Millisecond)}, wantRequests: 1},
+		{name: "html error page", faults: []hudsonrocktest.Fault{hudsonrocktest.HTMLError(http.StatusForbidden)}, wantErr: true, wantRequests: 1},
+		{name: "html error page retried", faults: []hudsonrocktest.Fault{hudsonrocktest.HTMLError(http.StatusBadGateway)}, wantRequests: 2},
+		{name: "malformed json retried", faults: []hudsonrocktest.Fault{hudsonrocktest.MalformedJSON()}, wantRequests: 2},
+		{name: "malformed json exhausts retries", faults: hudsonrocktest.Repeat(3, hudsonrocktest.MalformedJSON()), wantErr: true, wantRequests: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, server := newTestClient(t)
+			server.Inject(hudsonrocktest.EndpointSearchByIP, tt.faults...)
+
+			result, err := client.SearchByIp(hudsonrocktest.Context(), "192.0.2.10")
+			if got := server.RequestCount(hudsonrocktest.EndpointSearchByIP); got != tt.wantRequests {
+				t.Errorf("got %d requests, want %d", got, tt.wantRequests)
+			}
+			if tt.wantErr {
+				if err == nil {
+					t.Fatalf("expected an error, got %+v", result)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SearchByIp: %v", err)
+			}
+			if len(result.Stealers) != 1 {
+				t.Errorf("got %d stealers, want 1", len(result.Stealers))
+			}
+		})
+	}
+}
+
+func TestClientSlowResponseTimeout(t *testing.T) {
+	client, server := newTestClient(t)
+	client.WithMaxRetries(1)
+	server.Inject(hudsonrocktest.AnyEndpoint, hudsonrocktest.Slow(time.Minute))
+
+	ctx, cancel := context.WithTimeout(hudsonrocktest.Context(), 100*time.Millisecond)
+	defer cancel()
+	if _, err := client.SearchByUsername(ctx, "jdoe"); err == nil {
+		t.Fatal("expected an error for a response slower than the context deadline")
+	}
+}
@@ -0,0 +1,227 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Strict decoding modes. With StrictDecodingOff unknown fields are ignored
+// and mistyped fields fail the request, with StrictDecodingLog both are
+// logged as warnings and the rest of the response is kept, and with
+// StrictDecodingError both fail the request.
+const (
+	StrictDecodingOff   = "off"
+	StrictDecodingLog   = "log"
+	StrictDecodingError = "error"
+)
+
+// ErrSchemaDrift is returned when a response does not match the response
+// structs. It is never retried, as the next attempt gets the same response.
+var ErrSchemaDrift = errors.New("response does not match the expected schema")
+
+var jsonUnmarshalerType = reflect.TypeOf((*json.Unmarshaler)(nil)).Elem()
+
+// rawSetter is implemented by responses that keep their undecoded body.
+type rawSetter interface {
+	setRaw(body json.RawMessage)
+}
+
+// ParseStrictDecoding returns the strict decoding mode named by mode, where
+// "" is StrictDecodingOff.
+func ParseStrictDecoding(mode string) (string, error) {
+	switch mode {
+	case "":
+		return StrictDecodingOff, nil
+	case StrictDecodingOff, StrictDecodingLog, StrictDecodingError:
+		return mode, nil
+	}
+	return "", fmt.Errorf("invalid strict decoding mode %q, must be one of %s, %s or %s", mode, StrictDecodingOff, StrictDecodingLog, StrictDecodingError)
+}
+
+// WithStrictDecoding sets how responses with unknown or mistyped fields are
+// handled.
+func (c *Client) WithStrictDecoding(mode string) (*Client, error) {
+	mode, err := ParseStrictDecoding(mode)
+	if err != nil {
+		return c, err
+	}
+	c.StrictDecoding = mode
+	return c, nil
+}
+
+// decode unmarshals the body of a response from endpoint into v, checks it
+// for schema drift according to the client's strict decoding mode and keeps
+// the undecoded body on v. Syntax errors are returned as is so that a
+// truncated body is retried.
+func (c *Client) decode(ctx context.Context, endpoint string, body []byte, v any) error {
+	problems, err := c.unmarshal(body, v, "")
+	if err != nil {
+		return err
+	}
+	if err := c.checkDrift(ctx, endpoint, problems); err != nil {
+		return err
+	}
+
+	if setter, ok := v.(rawSetter); ok {
+		setter.setRaw(body)
+	}
+	return nil
+}
+
+// unmarshal unmarshals body, found at path in the response, into v and
+// returns the schema drift problems found according to the client's strict
+// decoding mode.
+func (c *Client) unmarshal(body []byte, v any, path string) ([]string, error) {
+	var problems []string
+
+	err := json.Unmarshal(body, v)
+	if problem, ok := typeProblem(err, path); ok {
+		problems = append(problems, problem)
+	} else if err != nil {
+		return nil, err
+	}
+
+	if c.StrictDecoding == StrictDecodingLog || c.StrictDecoding == StrictDecodingError {
+		var generic any
+		if err := json.Unmarshal(body, &generic); err != nil {
+			return nil, err
+		}
+		for _, field := range unknownFields(generic, reflect.TypeOf(v), path) {
+			problems = append(problems, "unknown field "+field)
+		}
+	}
+	return problems, nil
+}
+
+// typeProblem describes err if it is a type error, which Unmarshal reports
+// for the first mistyped field after decoding the rest.
+func typeProblem(err error, path string) (string, bool) {
+	var typeErr *json.UnmarshalTypeError
+	if !errors.As(err, &typeErr) {
+		return "", false
+	}
+	// Errors of the tolerant scalars abort Unmarshal before it names the field
+	field := path
+	if typeErr.Field != "" {
+		field = joinPath(path, typeErr.Field)
+	}
+	return fmt.Sprintf("field %s: %s cannot be decoded into %s", field, typeErr.Value, typeErr.Type), true
+}
+
+// checkDrift logs the schema drift problems of a response from endpoint, or
+// returns them as an ErrSchemaDrift, depending on the strict decoding mode.
+func (c *Client) checkDrift(ctx context.Context, endpoint string, problems []string) error {
+	if len(problems) == 0 {
+		return nil
+	}
+	if c.StrictDecoding == StrictDecodingLog {
+		plugin.Logger(ctx).Warn("schema drift", "endpoint", endpoint, "problems", strings.Join(problems, "; "))
+		return nil
+	}
+	return fmt.Errorf("%s: %w: %s", endpoint, ErrSchemaDrift, strings.Join(problems, "; "))
+}
+
+// unknownFields returns the sorted paths of the object keys in value that
+// have no matching field in t. Keys are matched case-insensitively, as
+// encoding/json does. Elements of arrays are reported under "[]".
+func unknownFields(value any, t reflect.Type, path string) []string {
+	seen := map[string]struct{}{}
+	collectUnknownFields(value, t, path, seen)
+
+	fields := make([]string, 0, len(seen))
+	for field := range seen {
+		fields = append(fields, field)
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+func collectUnknownFields(value any, t reflect.Type, path string, seen map[string]struct{}) {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	// Types decoding themselves accept whatever they accept
+	if t.Implements(jsonUnmarshalerType) || reflect.PointerTo(t).Implements(jsonUnmarshalerType) {
+		return
+	}
+
+	switch t.Kind() {
+	case reflect.Struct:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		fields := jsonFields(t)
+		for key, child := range object {
+			fieldType, ok := fields[strings.ToLower(key)]
+			if !ok {
+				seen[joinPath(path, key)] = struct{}{}
+				continue
+			}
+			collectUnknownFields(child, fieldType, joinPath(path, key), seen)
+		}
+	case reflect.Slice, reflect.Array:
+		elements, ok := value.([]any)
+		if !ok {
+			return
+		}
+		for _, element := range elements {
+			collectUnknownFields(element, t.Elem(), path+"[]", seen)
+		}
+	case reflect.Map:
+		object, ok := value.(map[string]any)
+		if !ok {
+			return
+		}
+		for _, child := range object {
+			collectUnknownFields(child, t.Elem(), path+".*", seen)
+		}
+	}
+}
+
+// jsonFields returns the types of the fields of struct type t by lower-cased
+// JSON name.
+func jsonFields(t reflect.Type) map[string]reflect.Type {
+	fields := map[string]reflect.Type{}
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		fields[strings.ToLower(name)] = field.Type
+	}
+	return fields
+}
+
+func joinPath(path, key string) string {
+	if path == "" {
+		return key
+	}
+	return path + "." + key
+}
+
+// stealerObjects returns the undecoded objects of the stealers array of a
+// response body, or nil if there are not n of them.
+func stealerObjects(body json.RawMessage, n int) []json.RawMessage {
+	var response struct {
+		Stealers []json.RawMessage `json:"stealers"`
+	}
+	if err := json.Unmarshal(body, &response); err != nil || len(response.Stealers) != n {
+		return nil
+	}
+	return response.Stealers
+}
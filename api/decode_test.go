@@ -0,0 +1,125 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+)
+
+// driftedEmail has an unknown top-level field, an unknown stealer field and
+// a number where a string is expected.
+const driftedEmail = `{"message":"found","stealers":[{"computer_name":"PC-1","ip":"192.0.2.1","stealer_family":"Vidar"},{"computer_name":42}],"total_corporate_services":1,"total_user_services":0,"credit_cards":3}`
+
+func TestFixturesDecodeStrictly(t *testing.T) {
+	client, _ := newTestClient(t)
+	if _, err := client.WithStrictDecoding(StrictDecodingError); err != nil {
+		t.Fatal(err)
+	}
+	ctx := hudsonrocktest.Context()
+
+	if _, err := client.SearchByEmail(ctx, "jane.doe@example.com"); err != nil {
+		t.Errorf("SearchByEmail: %v", err)
+	}
+	if _, err := client.SearchByIp(ctx, "192.0.2.10"); err != nil {
+		t.Errorf("SearchByIp: %v", err)
+	}
+	if _, err := client.SearchByUsername(ctx, "jdoe"); err != nil {
+		t.Errorf("SearchByUsername: %v", err)
+	}
+	if _, err := client.SearchByDomain(ctx, "example.com"); err != nil {
+		t.Errorf("SearchByDomain: %v", err)
+	}
+	if _, err := client.UrlByDomain(ctx, "example.com"); err != nil {
+		t.Errorf("UrlByDomain: %v", err)
+	}
+}
+
+func TestStrictDecodingModes(t *testing.T) {
+	tests := []struct {
+		mode     string
+		wantErr  []string
+		stealers int
+	}{
+		{mode: StrictDecodingOff, wantErr: []string{"field stealers.1.computer_name: number cannot be decoded into string"}},
+		{mode: StrictDecodingLog, stealers: 2},
+		{mode: StrictDecodingError, wantErr: []string{"field stealers.1.computer_name", "unknown field credit_cards", "unknown field stealers[].stealer_family"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.mode, func(t *testing.T) {
+			client, server := newTestClient(t)
+			if _, err := client.WithStrictDecoding(tt.mode); err != nil {
+				t.Fatal(err)
+			}
+			server.SetResponse(hudsonrocktest.EndpointSearchByEmail, "drift@example.com", []byte(driftedEmail))
+
+			result, err := client.SearchByEmail(hudsonrocktest.Context(), "drift@example.com")
+			if got := server.RequestCount(hudsonrocktest.EndpointSearchByEmail); got != 1 {
+				t.Errorf("made %d requests, want 1", got)
+			}
+			if len(tt.wantErr) > 0 {
+				if !errors.Is(err, ErrSchemaDrift) {
+					t.Fatalf("got error %v, want ErrSchemaDrift", err)
+				}
+				for _, want := range tt.wantErr {
+					if !strings.Contains(err.Error(), want) {
+						t.Errorf("error %q does not contain %q", err, want)
+					}
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("SearchByEmail: %v", err)
+			}
+			if len(result.Stealers) != tt.stealers || result.Stealers[0].ComputerName != "PC-1" {
+				t.Errorf("unexpected stealers %+v", result.Stealers)
+			}
+		})
+	}
+}
+
+func TestRawPayloads(t *testing.T) {
+	client, server := newTestClient(t)
+	server.SetResponse(hudsonrocktest.EndpointSearchByEmail, "drift@example.com", []byte(strings.Replace(driftedEmail, `{"computer_name":42}`, `{"computer_name":"PC-2"}`, 1)))
+
+	result, err := client.SearchByEmail(hudsonrocktest.Context(), "drift@example.com")
+	if err != nil {
+		t.Fatalf("SearchByEmail: %v", err)
+	}
+
+	var response map[string]any
+	if err := json.Unmarshal(result.Raw, &response); err != nil || response["credit_cards"] != float64(3) {
+		t.Errorf("Raw = %s, want the full response", result.Raw)
+	}
+	var stealer map[string]any
+	if err := json.Unmarshal(result.Stealers[0].Raw, &stealer); err != nil || stealer["stealer_family"] != "Vidar" {
+		t.Errorf("Stealers[0].Raw = %s, want the first stealer object", result.Stealers[0].Raw)
+	}
+	if string(result.Stealers[1].Raw) != `{"computer_name":"PC-2"}` {
+		t.Errorf("Stealers[1].Raw = %s", result.Stealers[1].Raw)
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	var value any
+	body := `{"Total":1,"stats":{"totalEmployees":1,"extra":true},"stealerFamilies":{"Lumma":1},"thirdPartyDomains":[{"domain":"a.com","new":1},{"domain":null,"new":2}],"data":{"all_urls":[{"H":"x","rank":1}]}}`
+	if err := json.Unmarshal([]byte(body), &value); err != nil {
+		t.Fatal(err)
+	}
+
+	got := unknownFields(value, reflect.TypeOf(DomainSearchResponse{}), "")
+	want := []string{"data.all_urls[].rank", "stats.extra", "thirdPartyDomains[].new"}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("unknownFields = %v, want %v", got, want)
+	}
+}
+
+func TestWithStrictDecodingRejectsUnknownModes(t *testing.T) {
+	if _, err := NewClient().WithStrictDecoding("warn"); err == nil {
+		t.Error("WithStrictDecoding(\"warn\") succeeded")
+	}
+}
@@ -0,0 +1,80 @@
+package api
+
+import (
+	"bufio"
+	_ "embed"
+	"sort"
+	"strings"
+)
+
+//go:embed data/freemail_domains.txt
+var freemailDomainsFile string
+
+// freemailDomains is the set of free and disposable mail provider domains.
+var freemailDomains = parseDomainList(freemailDomainsFile)
+
+func parseDomainList(list string) map[string]struct{} {
+	domains := make(map[string]struct{})
+	scanner := bufio.NewScanner(strings.NewReader(list))
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		domains[strings.ToLower(line)] = struct{}{}
+	}
+	return domains
+}
+
+// EmailDomain returns the lower-cased domain part of an email address, or an
+// empty string if the address has no usable domain. Domains that have been
+// masked by the API (containing '*') are treated as unusable.
+func EmailDomain(email string) string {
+	at := strings.LastIndex(email, "@")
+	if at < 0 || at == len(email)-1 {
+		return ""
+	}
+	domain := strings.ToLower(strings.TrimSpace(email[at+1:]))
+	domain = strings.TrimSuffix(domain, ".")
+	if domain == "" || strings.Contains(domain, "*") || !strings.Contains(domain, ".") {
+		return ""
+	}
+	return domain
+}
+
+// IsFreemailDomain reports whether the domain, or any parent of it, belongs
+// to a known free or disposable mail provider.
+func IsFreemailDomain(domain string) bool {
+	domain = strings.ToLower(strings.TrimSpace(domain))
+	for domain != "" {
+		if _, ok := freemailDomains[domain]; ok {
+			return true
+		}
+		dot := strings.Index(domain, ".")
+		if dot < 0 {
+			break
+		}
+		domain = domain[dot+1:]
+	}
+	return false
+}
+
+// CorporateLoginDomains extracts the distinct, sorted domains of the given
+// logins that do not belong to a free or disposable mail provider.
+func CorporateLoginDomains(logins []string) []string {
+	seen := make(map[string]struct{})
+	var domains []string
+	for _, login := range logins {
+		domain := EmailDomain(login)
+		if domain == "" || IsFreemailDomain(domain) {
+			continue
+		}
+		if _, ok := seen[domain]; ok {
+			continue
+		}
+		seen[domain] = struct{}{}
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	return domains
+}
@@ -0,0 +1,20 @@
+// Command hudsonrock-mock serves the hudsonrocktest fixtures over HTTP so the
+// plugin can be run without network access by pointing the connection's
+// base_url at it.
+package main
+
+import (
+	"flag"
+	"log"
+	"net/http"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+)
+
+func main() {
+	addr := flag.String("addr", "127.0.0.1:8089", "address to listen on")
+	flag.Parse()
+
+	log.Printf("serving Hudson Rock fixtures on http://%s", *addr)
+	log.Fatal(http.ListenAndServe(*addr, hudsonrocktest.NewHandler()))
+}
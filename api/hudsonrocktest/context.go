@@ -0,0 +1,14 @@
+package hudsonrocktest
+
+import (
+	"context"
+
+	"github.com/hashicorp/go-hclog"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/context_key"
+)
+
+// Context returns a context carrying the logger that plugin.Logger expects,
+// for calling API client methods outside of a running plugin.
+func Context() context.Context {
+	return context.WithValue(context.Background(), context_key.Logger, hclog.NewNullLogger())
+}
@@ -0,0 +1,105 @@
+package hudsonrocktest
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Fault is a scripted misbehaviour of the server for a single request. A
+// fault with a zero Status only delays the response, which is then served
+// normally.
+type Fault struct {
+	// Status is the HTTP status code returned.
+	Status int
+	// RetryAfter, if set, is sent in the Retry-After header in whole seconds.
+	RetryAfter time.Duration
+	// Delay is waited before responding, or until the client gives up.
+	Delay time.Duration
+	// ContentType and Body are the response returned with Status.
+	ContentType string
+	Body        string
+}
+
+// RateLimited returns a 429 Too Many Requests fault asking the client to
+// retry after the given duration.
+func RateLimited(retryAfter time.Duration) Fault {
+	return Fault{
+		Status:      http.StatusTooManyRequests,
+		RetryAfter:  retryAfter,
+		ContentType: "application/json",
+		Body:        `{"error":"Too many requests, please try again later."}`,
+	}
+}
+
+// ServerError returns a fault answering with a 5xx status and a JSON error
+// body.
+func ServerError(status int) Fault {
+	return Fault{
+		Status:      status,
+		ContentType: "application/json",
+		Body:        fmt.Sprintf(`{"error":%q}`, http.StatusText(status)),
+	}
+}
+
+// Slow returns a fault delaying the normal response.
+func Slow(delay time.Duration) Fault {
+	return Fault{Delay: delay}
+}
+
+// MalformedJSON returns a fault answering 200 OK with a truncated JSON body.
+func MalformedJSON() Fault {
+	return Fault{
+		Status:      http.StatusOK,
+		ContentType: "application/json",
+		Body:        `{"message":"This email address is associated with a computer","stealers":[{"computer_name":"DESKTOP-`,
+	}
+}
+
+// HTMLError returns a fault answering with an HTML error page, as returned by
+// load balancers and CDNs in front of the API.
+func HTMLError(status int) Fault {
+	text := http.StatusText(status)
+	return Fault{
+		Status:      status,
+		ContentType: "text/html; charset=utf-8",
+		Body:        fmt.Sprintf("<!DOCTYPE html>\n<html><head><title>%d %s</title></head><body><h1>%d %s</h1><hr><center>nginx</center></body></html>\n", status, text, status, text),
+	}
+}
+
+// Repeat returns n copies of a fault, e.g. for a burst of server errors.
+func Repeat(n int, fault Fault) []Fault {
+	faults := make([]Fault, n)
+	for i := range faults {
+		faults[i] = fault
+	}
+	return faults
+}
+
+// serve writes the fault's response. It returns false if the request should
+// then be answered normally.
+func (f Fault) serve(w http.ResponseWriter, r *http.Request) bool {
+	if f.Delay > 0 {
+		timer := time.NewTimer(f.Delay)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-r.Context().Done():
+			return true
+		}
+	}
+	if f.Status == 0 {
+		return false
+	}
+
+	if f.RetryAfter > 0 {
+		w.Header().Set("Retry-After", strconv.Itoa(int(f.RetryAfter.Round(time.Second)/time.Second)))
+	}
+	if f.ContentType != "" {
+		w.Header().Set("Content-Type", f.ContentType)
+	}
+	w.WriteHeader(f.Status)
+	_, _ = w.Write([]byte(f.Body))
+	return true
+}
@@ -0,0 +1,198 @@
+// Package hudsonrocktest provides an offline implementation of the Hudson Rock
+// osint-tools API for tests and local development.
+//
+// The server answers every endpoint with the fixture JSON embedded in the
+// fixtures directory, or with a response set for a specific query value.
+// Faults such as rate limiting, server error bursts, slow responses and
+// malformed bodies can be scripted per endpoint:
+//
+//	server := hudsonrocktest.NewServer()
+//	defer server.Close()
+//	server.Inject(hudsonrocktest.EndpointSearchByEmail, hudsonrocktest.Repeat(2, hudsonrocktest.ServerError(503))...)
+//	client := api.NewClient().WithBaseURL(server.URL)
+package hudsonrocktest
+
+import (
+	"embed"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path"
+	"strings"
+	"sync"
+)
+
+// Paths of the osint-tools endpoints served.
+const (
+	EndpointSearchByDomain   = "/api/json/v2/osint-tools/search-by-domain"
+	EndpointSearchByEmail    = "/api/json/v2/osint-tools/search-by-email"
+	EndpointSearchByIP       = "/api/json/v2/osint-tools/search-by-ip"
+	EndpointSearchByUsername = "/api/json/v2/osint-tools/search-by-username"
+	EndpointURLsByDomain     = "/api/json/v2/osint-tools/urls-by-domain"
+)
+
+// AnyEndpoint may be passed to Inject to script faults returned by whichever
+// endpoint is requested next.
+const AnyEndpoint = "*"
+
+// queryParams maps every endpoint to the query parameter holding the value
+// looked up.
+var queryParams = map[string]string{
+	EndpointSearchByDomain:   "domain",
+	EndpointSearchByEmail:    "email",
+	EndpointSearchByIP:       "ip",
+	EndpointSearchByUsername: "username",
+	EndpointURLsByDomain:     "domain",
+}
+
+//go:embed fixtures/*.json
+var fixtures embed.FS
+
+// Fixture returns the default response body of an endpoint.
+func Fixture(endpoint string) ([]byte, error) {
+	if _, ok := queryParams[endpoint]; !ok {
+		return nil, fmt.Errorf("unknown endpoint %q", endpoint)
+	}
+	return fixtures.ReadFile("fixtures/" + path.Base(endpoint) + ".json")
+}
+
+// Request is a request received by the server.
+type Request struct {
+	Endpoint string
+	Query    url.Values
+	// Fault is the scripted fault that answered the request, if any.
+	Fault *Fault
+}
+
+// Handler serves the osint-tools endpoints. It is safe for concurrent use.
+type Handler struct {
+	mu        sync.Mutex
+	responses map[string]map[string][]byte
+	faults    map[string][]Fault
+	requests  []Request
+}
+
+// NewHandler returns a Handler serving the embedded fixtures.
+func NewHandler() *Handler {
+	return &Handler{
+		responses: map[string]map[string][]byte{},
+		faults:    map[string][]Fault{},
+	}
+}
+
+// SetResponse serves body instead of the fixture when endpoint is queried
+// for value. Values are matched case-insensitively.
+func (h *Handler) SetResponse(endpoint, value string, body []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.responses[endpoint] == nil {
+		h.responses[endpoint] = map[string][]byte{}
+	}
+	h.responses[endpoint][strings.ToLower(value)] = body
+}
+
+// Inject queues faults for an endpoint, or for AnyEndpoint. Each request
+// consumes the next queued fault; once the queue is empty the endpoint
+// answers normally again. Faults queued for the endpoint itself are used
+// before those queued for AnyEndpoint.
+func (h *Handler) Inject(endpoint string, faults ...Fault) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.faults[endpoint] = append(h.faults[endpoint], faults...)
+}
+
+// Reset drops all queued faults, response overrides and recorded requests.
+func (h *Handler) Reset() {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	h.responses = map[string]map[string][]byte{}
+	h.faults = map[string][]Fault{}
+	h.requests = nil
+}
+
+// Requests returns the requests received so far, in order.
+func (h *Handler) Requests() []Request {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	return append([]Request(nil), h.requests...)
+}
+
+// RequestCount returns the number of requests received by an endpoint, or
+// by all endpoints for AnyEndpoint.
+func (h *Handler) RequestCount(endpoint string) int {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	count := 0
+	for _, r := range h.requests {
+		if endpoint == AnyEndpoint || r.Endpoint == endpoint {
+			count++
+		}
+	}
+	return count
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	endpoint := r.URL.Path
+	param, ok := queryParams[endpoint]
+	if !ok || r.Method != http.MethodGet {
+		http.NotFound(w, r)
+		return
+	}
+
+	fault, body := h.next(endpoint, r.URL.Query())
+	if fault != nil {
+		if done := fault.serve(w, r); done {
+			return
+		}
+	}
+
+	if body == nil {
+		fixture, err := Fixture(endpoint)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		body = fixture
+	}
+	if r.URL.Query().Get(param) == "" {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusBadRequest)
+		fmt.Fprintf(w, `{"error":"%s is required"}`, param)
+		return
+	}
+	w.Header().Set("Content-Type", "application/json")
+	_, _ = w.Write(body)
+}
+
+// next records the request and returns the fault answering it, if any, and
+// the response override for its query value.
+func (h *Handler) next(endpoint string, query url.Values) (*Fault, []byte) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	var fault *Fault
+	for _, key := range []string{endpoint, AnyEndpoint} {
+		if queue := h.faults[key]; len(queue) > 0 {
+			f := queue[0]
+			h.faults[key] = queue[1:]
+			fault = &f
+			break
+		}
+	}
+	h.requests = append(h.requests, Request{Endpoint: endpoint, Query: query, Fault: fault})
+	return fault, h.responses[endpoint][strings.ToLower(query.Get(queryParams[endpoint]))]
+}
+
+// Server is a started httptest.Server running a Handler.
+type Server struct {
+	*httptest.Server
+	*Handler
+}
+
+// NewServer starts a server serving the embedded fixtures. The caller must
+// call Close when done.
+func NewServer() *Server {
+	handler := NewHandler()
+	return &Server{Server: httptest.NewServer(handler), Handler: handler}
+}
@@ -0,0 +1,80 @@
+package hudsonrocktest
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func get(t *testing.T, url string) (*http.Response, string) {
+	t.Helper()
+	resp, err := http.Get(url)
+	if err != nil {
+		t.Fatalf("GET %s: %v", url, err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		t.Fatalf("reading body: %v", err)
+	}
+	return resp, string(body)
+}
+
+func TestFixturesAreValidJSON(t *testing.T) {
+	for endpoint := range queryParams {
+		body, err := Fixture(endpoint)
+		if err != nil {
+			t.Fatalf("Fixture(%s): %v", endpoint, err)
+		}
+		if !json.Valid(body) {
+			t.Errorf("fixture for %s is not valid JSON", endpoint)
+		}
+	}
+}
+
+func TestServerFaultScript(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+	url := server.URL + EndpointSearchByEmail + "?email=jane.doe@example.com"
+
+	server.Inject(EndpointSearchByEmail, RateLimited(2*time.Second), HTMLError(http.StatusBadGateway))
+	server.Inject(AnyEndpoint, ServerError(http.StatusServiceUnavailable))
+
+	resp, _ := get(t, url)
+	if resp.StatusCode != http.StatusTooManyRequests || resp.Header.Get("Retry-After") != "2" {
+		t.Errorf("first response: status %d, Retry-After %q", resp.StatusCode, resp.Header.Get("Retry-After"))
+	}
+	resp, body := get(t, url)
+	if resp.StatusCode != http.StatusBadGateway || resp.Header.Get("Content-Type") != "text/html; charset=utf-8" || json.Valid([]byte(body)) {
+		t.Errorf("second response: status %d, content type %q", resp.StatusCode, resp.Header.Get("Content-Type"))
+	}
+	resp, _ = get(t, url)
+	if resp.StatusCode != http.StatusServiceUnavailable {
+		t.Errorf("third response: status %d, want %d", resp.StatusCode, http.StatusServiceUnavailable)
+	}
+	resp, body = get(t, url)
+	if resp.StatusCode != http.StatusOK || !json.Valid([]byte(body)) {
+		t.Errorf("fourth response: status %d, want %d with the fixture", resp.StatusCode, http.StatusOK)
+	}
+
+	if got := server.RequestCount(EndpointSearchByEmail); got != 4 {
+		t.Errorf("RequestCount = %d, want 4", got)
+	}
+	if requests := server.Requests(); requests[3].Fault != nil {
+		t.Errorf("fourth request answered by fault %+v", requests[3].Fault)
+	}
+}
+
+func TestServerRejectsBadRequests(t *testing.T) {
+	server := NewServer()
+	defer server.Close()
+
+	if resp, _ := get(t, server.URL+"/api/json/v2/osint-tools/unknown"); resp.StatusCode != http.StatusNotFound {
+		t.Errorf("unknown endpoint: status %d, want %d", resp.StatusCode, http.StatusNotFound)
+	}
+	if resp, _ := get(t, server.URL+EndpointSearchByIP); resp.StatusCode != http.StatusBadRequest {
+		t.Errorf("missing query parameter: status %d, want %d", resp.StatusCode, http.StatusBadRequest)
+	}
+}
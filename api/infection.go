@@ -0,0 +1,52 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"strings"
+	"time"
+)
+
+// InfectionID returns a deterministic fingerprint for an infected machine,
+// derived from its normalized computer name, compromise date, malware path and
+// IP address. The same infection returned by different lookups (email, IP or
+// username) yields the same ID, so rows can be deduplicated across tables.
+func InfectionID(computerName, dateCompromised, malwarePath, ip string) string {
+	parts := []string{
+		strings.ToLower(strings.TrimSpace(computerName)),
+		normalizeDateCompromised(dateCompromised),
+		normalizeMalwarePath(malwarePath),
+		strings.ToLower(strings.TrimSpace(ip)),
+	}
+	sum := sha256.Sum256([]byte(strings.Join(parts, "\x1f")))
+	return hex.EncodeToString(sum[:16])
+}
+
+// InfectionID returns the fingerprint of the infection.
+func (s EmailStealer) InfectionID() string {
+	return InfectionID(s.ComputerName, s.DateCompromised, s.MalwarePath, s.IP)
+}
+
+// InfectionID returns the fingerprint of the infection.
+func (s IPStealer) InfectionID() string {
+	return InfectionID(s.ComputerName, s.DateCompromised, s.MalwarePath, s.IP)
+}
+
+// InfectionID returns the fingerprint of the infection.
+func (s UsernameStealer) InfectionID() string {
+	return InfectionID(s.ComputerName, s.DateCompromised, s.MalwarePath, s.IP)
+}
+
+// normalizeDateCompromised renders parseable timestamps in a single UTC form
+// so that differences in precision or offset do not change the fingerprint.
+func normalizeDateCompromised(date string) string {
+	if t, ok := ParseDate(date); ok {
+		return t.Format(time.RFC3339)
+	}
+	return strings.ToLower(strings.TrimSpace(date))
+}
+
+// normalizeMalwarePath lower-cases the path and unifies path separators.
+func normalizeMalwarePath(path string) string {
+	return strings.ToLower(strings.ReplaceAll(strings.TrimSpace(path), "/", `\`))
+}
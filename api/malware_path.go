@@ -0,0 +1,121 @@
+package api
+
+import (
+	"strings"
+)
+
+// Malware location classes returned in MalwarePathInfo.LocationClass.
+const (
+	MalwareLocationTemp         = "temp"
+	MalwareLocationDownloads    = "downloads"
+	MalwareLocationAppData      = "appdata"
+	MalwareLocationProgramFiles = "program_files"
+	MalwareLocationOther        = "other"
+)
+
+// MalwarePathInfo holds the forensic components of a malware path reported
+// for an infection.
+type MalwarePathInfo struct {
+	Drive         string
+	Directory     string
+	Filename      string
+	Extension     string
+	ProfileUser   string
+	LocationClass string
+}
+
+// ParseMalwarePath splits a Windows or macOS malware path into its drive,
+// directory, file name, extension and user profile, and classifies the
+// directory the malware ran from. An empty path returns a zero value.
+func ParseMalwarePath(path string) MalwarePathInfo {
+	path = strings.TrimSpace(path)
+	if path == "" {
+		return MalwarePathInfo{}
+	}
+
+	var info MalwarePathInfo
+
+	// Windows paths use backslashes and usually carry a drive letter, while
+	// macOS paths are rooted at '/'.
+	sep := "/"
+	if strings.Contains(path, `\`) || hasDriveLetter(path) {
+		sep = `\`
+		path = strings.ReplaceAll(path, "/", `\`)
+	}
+	if hasDriveLetter(path) {
+		info.Drive = strings.ToUpper(path[:2])
+	}
+
+	if i := strings.LastIndex(path, sep); i >= 0 {
+		info.Directory = path[:i]
+		info.Filename = path[i+1:]
+		if info.Directory == "" {
+			info.Directory = sep
+		} else if info.Directory == info.Drive {
+			info.Directory += sep
+		}
+	} else {
+		info.Filename = path
+	}
+	if i := strings.LastIndex(info.Filename, "."); i > 0 && i < len(info.Filename)-1 {
+		info.Extension = strings.ToLower(info.Filename[i+1:])
+	}
+
+	segments := strings.Split(info.Directory, sep)
+	for i, segment := range segments {
+		switch strings.ToLower(segment) {
+		case "users", "documents and settings", "home":
+			if i+1 < len(segments) && segments[i+1] != "" {
+				info.ProfileUser = segments[i+1]
+			}
+		}
+		if info.ProfileUser != "" {
+			break
+		}
+	}
+
+	info.LocationClass = classifyMalwareLocation(segments)
+	return info
+}
+
+func hasDriveLetter(path string) bool {
+	if len(path) < 2 || path[1] != ':' {
+		return false
+	}
+	c := path[0]
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// classifyMalwareLocation maps the directory segments of a malware path to a
+// location class. The deepest recognised folder wins, so a Temp folder inside
+// AppData is classified as temp.
+func classifyMalwareLocation(segments []string) string {
+	class := MalwareLocationOther
+	for i, segment := range segments {
+		switch strings.ToLower(segment) {
+		case "temp", "tmp", "temporary internet files", "inetcache":
+			class = MalwareLocationTemp
+		case "downloads":
+			class = MalwareLocationDownloads
+		case "appdata", "application data", "local settings", "programdata", "application support":
+			if class == MalwareLocationOther {
+				class = MalwareLocationAppData
+			}
+		case "program files", "program files (x86)":
+			if class == MalwareLocationOther {
+				class = MalwareLocationProgramFiles
+			}
+		case "applications":
+			// '/Applications' is the macOS equivalent of Program Files.
+			if class == MalwareLocationOther && i == 1 && segments[0] == "" {
+				class = MalwareLocationProgramFiles
+			}
+		case "folders":
+			// macOS per-user temporary files live in /private/var/folders.
+			if i > 0 && strings.EqualFold(segments[i-1], "var") {
+				class = MalwareLocationTemp
+			}
+		}
+	}
+	return class
+}
@@ -0,0 +1,47 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestParseMalwarePath(t *testing.T) {
+	tests := []struct {
+		input string
+		want  MalwarePathInfo
+	}{
+		{"", MalwarePathInfo{}},
+		{"   ", MalwarePathInfo{}},
+
+		// Windows
+		{`C:\Users\jdoe\AppData\Local\Temp\setup.exe`, MalwarePathInfo{Drive: "C:", Directory: `C:\Users\jdoe\AppData\Local\Temp`, Filename: "setup.exe", Extension: "exe", ProfileUser: "jdoe", LocationClass: MalwareLocationTemp}},
+		{`C:\Users\jdoe\Downloads\Invoice.PDF.EXE`, MalwarePathInfo{Drive: "C:", Directory: `C:\Users\jdoe\Downloads`, Filename: "Invoice.PDF.EXE", Extension: "exe", ProfileUser: "jdoe", LocationClass: MalwareLocationDownloads}},
+		{`C:\Users\jdoe\AppData\Roaming\updater.exe`, MalwarePathInfo{Drive: "C:", Directory: `C:\Users\jdoe\AppData\Roaming`, Filename: "updater.exe", Extension: "exe", ProfileUser: "jdoe", LocationClass: MalwareLocationAppData}},
+		{`C:\Program Files (x86)\Vendor\app.exe`, MalwarePathInfo{Drive: "C:", Directory: `C:\Program Files (x86)\Vendor`, Filename: "app.exe", Extension: "exe", LocationClass: MalwareLocationProgramFiles}},
+		{`C:\ProgramData\svc.exe`, MalwarePathInfo{Drive: "C:", Directory: `C:\ProgramData`, Filename: "svc.exe", Extension: "exe", LocationClass: MalwareLocationAppData}},
+		{`C:\Documents and Settings\Admin\Local Settings\Temp\x.exe`, MalwarePathInfo{Drive: "C:", Directory: `C:\Documents and Settings\Admin\Local Settings\Temp`, Filename: "x.exe", Extension: "exe", ProfileUser: "Admin", LocationClass: MalwareLocationTemp}},
+		{`c:/users/jdoe/desktop/run.bat`, MalwarePathInfo{Drive: "C:", Directory: `c:\users\jdoe\desktop`, Filename: "run.bat", Extension: "bat", ProfileUser: "jdoe", LocationClass: MalwareLocationOther}},
+		{`C:\payload.exe`, MalwarePathInfo{Drive: "C:", Directory: `C:\`, Filename: "payload.exe", Extension: "exe", LocationClass: MalwareLocationOther}},
+		{`C:\Users\jdoe\`, MalwarePathInfo{Drive: "C:", Directory: `C:\Users\jdoe`, ProfileUser: "jdoe", LocationClass: MalwareLocationOther}},
+		{`D:\tools\x.`, MalwarePathInfo{Drive: "D:", Directory: `D:\tools`, Filename: "x.", LocationClass: MalwareLocationOther}},
+		{`  C:\Temp\a.exe  `, MalwarePathInfo{Drive: "C:", Directory: `C:\Temp`, Filename: "a.exe", Extension: "exe", LocationClass: MalwareLocationTemp}},
+		{`\\server\share\drop.exe`, MalwarePathInfo{Directory: `\\server\share`, Filename: "drop.exe", Extension: "exe", LocationClass: MalwareLocationOther}},
+
+		// macOS and Unix
+		{"/Users/jane/Downloads/Installer.dmg", MalwarePathInfo{Directory: "/Users/jane/Downloads", Filename: "Installer.dmg", Extension: "dmg", ProfileUser: "jane", LocationClass: MalwareLocationDownloads}},
+		{"/Applications/Fake.app/Contents/MacOS/Fake", MalwarePathInfo{Directory: "/Applications/Fake.app/Contents/MacOS", Filename: "Fake", LocationClass: MalwareLocationProgramFiles}},
+		{"/Users/jane/Applications/Fake", MalwarePathInfo{Directory: "/Users/jane/Applications", Filename: "Fake", ProfileUser: "jane", LocationClass: MalwareLocationOther}},
+		{"/private/var/folders/xy/abc123/T/payload", MalwarePathInfo{Directory: "/private/var/folders/xy/abc123/T", Filename: "payload", LocationClass: MalwareLocationTemp}},
+		{"/Users/jane/Library/Application Support/agent/agent.bin", MalwarePathInfo{Directory: "/Users/jane/Library/Application Support/agent", Filename: "agent.bin", Extension: "bin", ProfileUser: "jane", LocationClass: MalwareLocationAppData}},
+		{"/home/user/.cache/miner", MalwarePathInfo{Directory: "/home/user/.cache", Filename: "miner", ProfileUser: "user", LocationClass: MalwareLocationOther}},
+		{"/tmp/.hidden", MalwarePathInfo{Directory: "/tmp", Filename: ".hidden", LocationClass: MalwareLocationTemp}},
+		{"/stealer", MalwarePathInfo{Directory: "/", Filename: "stealer", LocationClass: MalwareLocationOther}},
+
+		// No directory
+		{"setup.exe", MalwarePathInfo{Filename: "setup.exe", Extension: "exe", LocationClass: MalwareLocationOther}},
+	}
+	for _, tt := range tests {
+		if got := ParseMalwarePath(tt.input); got != tt.want {
+			t.Errorf("ParseMalwarePath(%q) = %+v, want %+v", tt.input, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,192 @@
+package api
+
+import (
+	"regexp"
+	"strings"
+)
+
+// Operating system families returned in OSInfo.Family.
+const (
+	OSFamilyWindows       = "windows"
+	OSFamilyWindowsServer = "windows_server"
+	OSFamilyMacOS         = "macos"
+	OSFamilyLinux         = "linux"
+	OSFamilyAndroid       = "android"
+	OSFamilyIOS           = "ios"
+	OSFamilyChromeOS      = "chromeos"
+	OSFamilyOther         = "other"
+)
+
+// OSInfo holds the components of an operating system string reported for an
+// infection, e.g. "Windows 10 Enterprise x64".
+type OSInfo struct {
+	Family              string
+	Version             string
+	Edition             string
+	Architecture        string
+	IsEnterpriseEdition bool
+}
+
+var (
+	osVersionPattern     = regexp.MustCompile(`^\d+(\.\d+)*$`)
+	osServicePackPattern = regexp.MustCompile(`^sp\d+$`)
+	osBuildPattern       = regexp.MustCompile(`\((build\s+)?[\d.]+\)|\bbuild\s+[\d.]+`)
+)
+
+// osFamilyPrefixes maps the leading words of an operating system string to
+// its family. Longer prefixes are listed first so they win over shorter ones.
+var osFamilyPrefixes = []struct {
+	words  []string
+	family string
+}{
+	{[]string{"windows", "server"}, OSFamilyWindowsServer},
+	{[]string{"windows"}, OSFamilyWindows},
+	{[]string{"win"}, OSFamilyWindows},
+	{[]string{"mac", "os", "x"}, OSFamilyMacOS},
+	{[]string{"mac", "os"}, OSFamilyMacOS},
+	{[]string{"macos"}, OSFamilyMacOS},
+	{[]string{"os", "x"}, OSFamilyMacOS},
+	{[]string{"osx"}, OSFamilyMacOS},
+	{[]string{"darwin"}, OSFamilyMacOS},
+	{[]string{"chrome", "os"}, OSFamilyChromeOS},
+	{[]string{"chromeos"}, OSFamilyChromeOS},
+	{[]string{"iphone", "os"}, OSFamilyIOS},
+	{[]string{"ios"}, OSFamilyIOS},
+	{[]string{"android"}, OSFamilyAndroid},
+	{[]string{"linux"}, OSFamilyLinux},
+	{[]string{"ubuntu"}, OSFamilyLinux},
+	{[]string{"debian"}, OSFamilyLinux},
+	{[]string{"fedora"}, OSFamilyLinux},
+	{[]string{"centos"}, OSFamilyLinux},
+}
+
+// osArchitectures maps architecture tokens to their canonical name.
+var osArchitectures = map[string]string{
+	"x64":     "x64",
+	"x86_64":  "x64",
+	"amd64":   "x64",
+	"64-bit":  "x64",
+	"64bit":   "x64",
+	"x86":     "x86",
+	"x32":     "x86",
+	"i386":    "x86",
+	"i686":    "x86",
+	"32-bit":  "x86",
+	"32bit":   "x86",
+	"arm64":   "arm64",
+	"aarch64": "arm64",
+	"arm":     "arm",
+}
+
+// osEditionWords holds the canonical spelling of well-known edition words.
+// Words not listed here are title-cased.
+var osEditionWords = map[string]string{
+	"pro":          "Pro",
+	"professional": "Pro",
+	"ltsc":         "LTSC",
+	"ltsb":         "LTSB",
+	"iot":          "IoT",
+	"n":            "N",
+	"kn":           "KN",
+	"for":          "for",
+}
+
+// windowsNamedVersions are Windows releases identified by name rather than
+// number.
+var windowsNamedVersions = map[string]string{
+	"xp":    "XP",
+	"vista": "Vista",
+	"me":    "ME",
+}
+
+// ParseOperatingSystem splits an operating system string into its family,
+// version, edition and architecture. Build numbers and service packs are
+// ignored. An empty string returns a zero value.
+func ParseOperatingSystem(operatingSystem string) OSInfo {
+	operatingSystem = strings.TrimSpace(operatingSystem)
+	if operatingSystem == "" {
+		return OSInfo{}
+	}
+
+	var info OSInfo
+
+	normalized := osBuildPattern.ReplaceAllString(strings.ToLower(operatingSystem), " ")
+	normalized = strings.NewReplacer("[", " ", "]", " ", "(", " ", ")", " ", ",", " ").Replace(normalized)
+
+	var tokens []string
+	for _, token := range strings.Fields(normalized) {
+		if arch, ok := osArchitectures[token]; ok {
+			if info.Architecture == "" {
+				info.Architecture = arch
+			}
+			continue
+		}
+		if osServicePackPattern.MatchString(token) {
+			continue
+		}
+		tokens = append(tokens, token)
+	}
+	if len(tokens) > 0 && tokens[0] == "microsoft" {
+		tokens = tokens[1:]
+	}
+
+	info.Family = OSFamilyOther
+	for _, prefix := range osFamilyPrefixes {
+		if hasTokenPrefix(tokens, prefix.words) {
+			info.Family = prefix.family
+			// Keep distribution names such as "Ubuntu" as the edition.
+			if prefix.family != OSFamilyLinux || prefix.words[0] == "linux" {
+				tokens = tokens[len(prefix.words):]
+			}
+			break
+		}
+	}
+
+	var edition []string
+	for _, token := range tokens {
+		if info.Version == "" {
+			if osVersionPattern.MatchString(token) {
+				info.Version = token
+				continue
+			}
+			if named, ok := windowsNamedVersions[token]; ok && info.Family == OSFamilyWindows {
+				info.Version = named
+				continue
+			}
+		}
+		edition = append(edition, canonicalEditionWord(token))
+	}
+	info.Edition = strings.Join(edition, " ")
+
+	switch {
+	case info.Family == OSFamilyWindowsServer:
+		info.IsEnterpriseEdition = true
+	case info.Family == OSFamilyWindows:
+		for _, token := range tokens {
+			if token == "enterprise" || token == "education" {
+				info.IsEnterpriseEdition = true
+			}
+		}
+	}
+
+	return info
+}
+
+func hasTokenPrefix(tokens, prefix []string) bool {
+	if len(tokens) < len(prefix) {
+		return false
+	}
+	for i, word := range prefix {
+		if tokens[i] != word {
+			return false
+		}
+	}
+	return true
+}
+
+func canonicalEditionWord(word string) string {
+	if canonical, ok := osEditionWords[word]; ok {
+		return canonical
+	}
+	return strings.ToUpper(word[:1]) + word[1:]
+}
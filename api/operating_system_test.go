@@ -0,0 +1,50 @@
+package api
+
+import (
+	"testing"
+)
+
+func TestParseOperatingSystem(t *testing.T) {
+	tests := []struct {
+		input string
+		want  OSInfo
+	}{
+		{"", OSInfo{}},
+		{"Windows 10 Enterprise x64", OSInfo{Family: "windows", Version: "10", Edition: "Enterprise", Architecture: "x64", IsEnterpriseEdition: true}},
+		{"Windows 11 Pro [x64]", OSInfo{Family: "windows", Version: "11", Edition: "Pro", Architecture: "x64"}},
+		{"Windows 10 Home x64", OSInfo{Family: "windows", Version: "10", Edition: "Home", Architecture: "x64"}},
+		{"Windows 10 Home Single Language x64", OSInfo{Family: "windows", Version: "10", Edition: "Home Single Language", Architecture: "x64"}},
+		{"Windows 11 Home (10.0.22621) x64", OSInfo{Family: "windows", Version: "11", Edition: "Home", Architecture: "x64"}},
+		{"Windows 10 Pro Build 19045 x64", OSInfo{Family: "windows", Version: "10", Edition: "Pro", Architecture: "x64"}},
+		{"Windows 10 Education [x64]", OSInfo{Family: "windows", Version: "10", Edition: "Education", Architecture: "x64", IsEnterpriseEdition: true}},
+		{"Windows 10 Enterprise LTSC 2019 x64", OSInfo{Family: "windows", Version: "10", Edition: "Enterprise LTSC 2019", Architecture: "x64", IsEnterpriseEdition: true}},
+		{"Windows 10 IoT Enterprise", OSInfo{Family: "windows", Version: "10", Edition: "IoT Enterprise", IsEnterpriseEdition: true}},
+		{"Windows 10 Pro N x64", OSInfo{Family: "windows", Version: "10", Edition: "Pro N", Architecture: "x64"}},
+		{"Windows 10 Pro for Workstations x64", OSInfo{Family: "windows", Version: "10", Edition: "Pro for Workstations", Architecture: "x64"}},
+		{"Microsoft Windows 10 Pro", OSInfo{Family: "windows", Version: "10", Edition: "Pro"}},
+		{"Windows 10 x64", OSInfo{Family: "windows", Version: "10", Architecture: "x64"}},
+		{"Windows 7 Professional SP1 x86", OSInfo{Family: "windows", Version: "7", Edition: "Pro", Architecture: "x86"}},
+		{"Windows 7 Ultimate x64", OSInfo{Family: "windows", Version: "7", Edition: "Ultimate", Architecture: "x64"}},
+		{"Windows 8.1 Pro x64", OSInfo{Family: "windows", Version: "8.1", Edition: "Pro", Architecture: "x64"}},
+		{"Windows XP Professional x86", OSInfo{Family: "windows", Version: "XP", Edition: "Pro", Architecture: "x86"}},
+		{"WINDOWS 11 PRO X64", OSInfo{Family: "windows", Version: "11", Edition: "Pro", Architecture: "x64"}},
+		{"Windows Server 2019 Standard x64", OSInfo{Family: "windows_server", Version: "2019", Edition: "Standard", Architecture: "x64", IsEnterpriseEdition: true}},
+		{"Windows Server 2022 Datacenter [x64]", OSInfo{Family: "windows_server", Version: "2022", Edition: "Datacenter", Architecture: "x64", IsEnterpriseEdition: true}},
+		{"MacOS 13.4", OSInfo{Family: "macos", Version: "13.4"}},
+		{"macOS Ventura 13.2.1", OSInfo{Family: "macos", Version: "13.2.1", Edition: "Ventura"}},
+		{"Mac OS X 10.15.7", OSInfo{Family: "macos", Version: "10.15.7"}},
+		{"macOS 14.1 (arm64)", OSInfo{Family: "macos", Version: "14.1", Architecture: "arm64"}},
+		{"Linux Ubuntu 20.04 x86_64", OSInfo{Family: "linux", Version: "20.04", Edition: "Ubuntu", Architecture: "x64"}},
+		{"Ubuntu 22.04", OSInfo{Family: "linux", Version: "22.04", Edition: "Ubuntu"}},
+		{"Android 12", OSInfo{Family: "android", Version: "12"}},
+		{"Unknown", OSInfo{Family: "other", Edition: "Unknown"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			if got := ParseOperatingSystem(tt.input); got != tt.want {
+				t.Errorf("ParseOperatingSystem(%q) = %+v, want %+v", tt.input, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,116 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"math"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// The API is not consistent about the JSON type of its counters: the same
+// field can come back as 12, 12.0, "12", "1,234" or null depending on the
+// endpoint and the day. Int, Int64 and Float accept all of these, decoding
+// null and empty strings as zero, so that one odd field does not fail a whole
+// response. They marshal as plain JSON numbers.
+
+// Int is an int decoded tolerantly from the API.
+type Int int
+
+// Int64 is an int64 decoded tolerantly from the API.
+type Int64 int64
+
+// Float is a float64 decoded tolerantly from the API. Strings may end with a
+// percent sign.
+type Float float64
+
+func (i *Int) UnmarshalJSON(data []byte) error {
+	f, err := parseTolerantNumber(data, reflect.TypeOf(*i))
+	if err != nil {
+		return err
+	}
+	if f >= math.MaxInt || f < math.MinInt {
+		return numberError(data, reflect.TypeOf(*i))
+	}
+	*i = Int(math.Round(f))
+	return nil
+}
+
+func (i *Int64) UnmarshalJSON(data []byte) error {
+	f, err := parseTolerantNumber(data, reflect.TypeOf(*i))
+	if err != nil {
+		return err
+	}
+	if f >= math.MaxInt64 || f < math.MinInt64 {
+		return numberError(data, reflect.TypeOf(*i))
+	}
+	*i = Int64(math.Round(f))
+	return nil
+}
+
+func (f *Float) UnmarshalJSON(data []byte) error {
+	value, err := parseTolerantNumber(data, reflect.TypeOf(*f))
+	if err != nil {
+		return err
+	}
+	*f = Float(value)
+	return nil
+}
+
+// parseTolerantNumber parses a JSON number, a string holding a number with
+// optional thousands separators, null or an empty string. Anything else is
+// reported as an *json.UnmarshalTypeError for t.
+func parseTolerantNumber(data []byte, t reflect.Type) (float64, error) {
+	data = bytes.TrimSpace(data)
+	if len(data) == 0 || string(data) == "null" {
+		return 0, nil
+	}
+
+	text := string(data)
+	if data[0] == '"' {
+		if err := json.Unmarshal(data, &text); err != nil {
+			return 0, numberError(data, t)
+		}
+		text = normalizeNumber(text)
+		if text == "" {
+			return 0, nil
+		}
+	}
+
+	f, err := strconv.ParseFloat(text, 64)
+	if err != nil || math.IsNaN(f) || math.IsInf(f, 0) {
+		return 0, numberError(data, t)
+	}
+	return f, nil
+}
+
+// normalizeNumber strips the thousands separators, surrounding spaces and
+// trailing percent sign from a numeric string.
+func normalizeNumber(s string) string {
+	s = strings.TrimSpace(s)
+	s = strings.TrimSuffix(s, "%")
+	return strings.Map(func(r rune) rune {
+		switch r {
+		case ',', '_', ' ', '\'', '\u00a0', '\u202f':
+			return -1
+		}
+		return r
+	}, s)
+}
+
+func numberError(data []byte, t reflect.Type) error {
+	value := "string"
+	switch {
+	case len(data) == 0:
+	case data[0] == '{':
+		value = "object"
+	case data[0] == '[':
+		value = "array"
+	case data[0] == 't' || data[0] == 'f':
+		value = "bool"
+	case data[0] != '"':
+		value = "number " + string(data)
+	}
+	return &json.UnmarshalTypeError{Value: value, Type: t}
+}
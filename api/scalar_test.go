@@ -0,0 +1,170 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+)
+
+func TestTolerantScalars(t *testing.T) {
+	tests := []struct {
+		input     string
+		wantInt   Int
+		wantFloat Float
+		wantErr   bool
+	}{
+		{input: `12`, wantInt: 12, wantFloat: 12},
+		{input: `12.0`, wantInt: 12, wantFloat: 12},
+		{input: `12.6`, wantInt: 13, wantFloat: 12.6},
+		{input: `-3`, wantInt: -3, wantFloat: -3},
+		{input: `1e3`, wantInt: 1000, wantFloat: 1000},
+		{input: `null`},
+		{input: `""`},
+		{input: `"  "`},
+		{input: `"42"`, wantInt: 42, wantFloat: 42},
+		{input: `"1,234,567"`, wantInt: 1234567, wantFloat: 1234567},
+		{input: `"1 234"`, wantInt: 1234, wantFloat: 1234},
+		{input: `"1_000"`, wantInt: 1000, wantFloat: 1000},
+		{input: `"1'000"`, wantInt: 1000, wantFloat: 1000},
+		{input: "\"1\u00a0000\"", wantInt: 1000, wantFloat: 1000},
+		{input: `"37.5%"`, wantInt: 38, wantFloat: 37.5},
+		{input: `"n/a"`, wantErr: true},
+		{input: `"NaN"`, wantErr: true},
+		{input: `"Infinity"`, wantErr: true},
+		{input: `true`, wantErr: true},
+		{input: `[1]`, wantErr: true},
+		{input: `{"value":1}`, wantErr: true},
+		{input: `1e400`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.input, func(t *testing.T) {
+			var i Int
+			err := json.Unmarshal([]byte(tt.input), &i)
+			if tt.wantErr {
+				var typeErr *json.UnmarshalTypeError
+				if !errors.As(err, &typeErr) {
+					t.Errorf("Int: got error %v, want an UnmarshalTypeError", err)
+				}
+			} else if err != nil || i != tt.wantInt {
+				t.Errorf("Int: got %d, %v, want %d", i, err, tt.wantInt)
+			}
+
+			var f Float
+			err = json.Unmarshal([]byte(tt.input), &f)
+			if tt.wantErr {
+				if err == nil {
+					t.Errorf("Float: got %v, want an error", f)
+				}
+			} else if err != nil || f != tt.wantFloat {
+				t.Errorf("Float: got %v, %v, want %v", f, err, tt.wantFloat)
+			}
+		})
+	}
+}
+
+func TestTolerantScalarsOutOfRange(t *testing.T) {
+	var i Int64
+	if err := json.Unmarshal([]byte(`9223372036854775808`), &i); err == nil {
+		t.Errorf("decoded 2^63 into Int64 as %d", i)
+	}
+	if err := json.Unmarshal([]byte(`"9,007,199,254,740,992"`), &i); err != nil || i != 1<<53 {
+		t.Errorf("got %d, %v, want %d", i, err, int64(1<<53))
+	}
+}
+
+func TestDomainSearchResponseTolerance(t *testing.T) {
+	body := `{"total":"1,204","totalStealers":null,"employees":37.0,"users":"1247","stats":{"employees_count":[3,"2",null]},"antiviruses":{"found":"61.5%"},"stealerFamilies":{"Lumma":"611","Vidar":null},"thirdPartyDomains":[{"occurrence":"12","domain":null}]}`
+
+	var result DomainSearchResponse
+	if err := json.Unmarshal([]byte(body), &result); err != nil {
+		t.Fatalf("Unmarshal: %v", err)
+	}
+	if result.Total != 1204 || result.TotalStealers != 0 || result.Employees != 37 || result.Users != 1247 {
+		t.Errorf("counters = %d, %d, %d, %d", result.Total, result.TotalStealers, result.Employees, result.Users)
+	}
+	if !reflect.DeepEqual(result.Stats.EmployeesCount, []Int{3, 2, 0}) {
+		t.Errorf("EmployeesCount = %v", result.Stats.EmployeesCount)
+	}
+	if result.Antiviruses.Found != 61.5 || result.StealerFamilies["Lumma"] != 611 || result.ThirdPartyDomains[0].Occurrence != 12 {
+		t.Errorf("unexpected result %+v", result)
+	}
+
+	encoded, err := json.Marshal(result.StealerFamilies)
+	if err != nil || string(encoded) != `{"Lumma":611,"Vidar":0}` {
+		t.Errorf("Marshal = %s, %v, want plain numbers", encoded, err)
+	}
+}
+
+func FuzzInt(f *testing.F) {
+	for _, seed := range []string{`0`, `-1`, `12.5`, `null`, `""`, `"1,234"`, `"50%"`, `"abc"`, `1e308`, `true`, `{}`} {
+		f.Add([]byte(seed))
+	}
+	f.Fuzz(func(t *testing.T, data []byte) {
+		var i Int
+		if err := i.UnmarshalJSON(data); err != nil {
+			return
+		}
+		// Whatever was accepted must survive a round trip
+		encoded, err := json.Marshal(i)
+		if err != nil {
+			t.Fatalf("Marshal(%d): %v", i, err)
+		}
+		var again Int
+		if err := json.Unmarshal(encoded, &again); err != nil || again != i {
+			t.Fatalf("round trip of %q: got %d, %v, want %d", data, again, err, i)
+		}
+	})
+}
+
+// FuzzResponses checks that no response body makes decoding panic, and that
+// decoded responses encode stably.
+func FuzzResponses(f *testing.F) {
+	for _, endpoint := range []string{
+		hudsonrocktest.EndpointSearchByDomain,
+		hudsonrocktest.EndpointSearchByEmail,
+		hudsonrocktest.EndpointSearchByIP,
+		hudsonrocktest.EndpointSearchByUsername,
+		hudsonrocktest.EndpointURLsByDomain,
+	} {
+		body, err := hudsonrocktest.Fixture(endpoint)
+		if err != nil {
+			f.Fatal(err)
+		}
+		f.Add(body)
+	}
+	f.Add([]byte(`{"total":"1,204","employees":null,"stealers":[{"total_user_services":"3"}],"stealerFamilies":{"Lumma":2.0}}`))
+
+	f.Fuzz(func(t *testing.T, body []byte) {
+		for _, newResponse := range []func() any{
+			func() any { return &DomainSearchResponse{} },
+			func() any { return &EmailSearchResponse{} },
+			func() any { return &IPSearchResponse{} },
+			func() any { return &UsernameSearchResponse{} },
+			func() any { return &URLSearchResponse{} },
+		} {
+			response := newResponse()
+			if err := json.Unmarshal(body, response); err != nil {
+				continue
+			}
+			encoded, err := json.Marshal(response)
+			if err != nil {
+				t.Fatalf("Marshal(%T): %v", response, err)
+			}
+			// Encoding may replace invalid UTF-8, so compare from the first
+			// encoding on
+			again := newResponse()
+			if err := json.Unmarshal(encoded, again); err != nil {
+				t.Fatalf("Unmarshal of re-encoded %T: %v", response, err)
+			}
+			reencoded, err := json.Marshal(again)
+			if err != nil || !bytes.Equal(encoded, reencoded) {
+				t.Fatalf("round trip of %T changed it:\n%s\n%s", response, encoded, reencoded)
+			}
+		}
+	})
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/url"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
@@ -10,13 +11,13 @@ import (
 
 // Define response struct
 type DomainSearchResponse struct {
-	Total                   int                `json:"total"`
-	TotalStealers           int64              `json:"totalStealers"`
-	Employees               int                `json:"employees"`
-	Users                   int                `json:"users"`
-	ThirdParties            int                `json:"third_parties"`
+	Total                   Int                `json:"total"`
+	TotalStealers           Int64              `json:"totalStealers"`
+	Employees               Int                `json:"employees"`
+	Users                   Int                `json:"users"`
+	ThirdParties            Int                `json:"third_parties"`
 	Logo                    string             `json:"logo"`
-	TotalUrls               int                `json:"totalUrls"`
+	TotalUrls               Int                `json:"totalUrls"`
 	Stats                   Stats              `json:"stats"`
 	IsShopify               bool               `json:"is_shopify"`
 	LastEmployeeCompromised string             `json:"last_employee_compromised"`
@@ -26,8 +27,13 @@ type DomainSearchResponse struct {
 	EmployeePasswords       PasswordStats      `json:"employeePasswords"`
 	UserPasswords           PasswordStats      `json:"userPasswords"`
 	ThirdPartyDomains       []DomainOccurrence `json:"thirdPartyDomains"`
-	StealerFamilies         map[string]int     `json:"stealerFamilies"`
+	StealerFamilies         map[string]Int     `json:"stealerFamilies"`
 	Data                    DomainSearchData   `json:"data"`
+	// Raw is the undecoded response, without the URL lists
+	Raw json.RawMessage `json:"-"`
+	// Truncated is set if URLs past the client's MaxResponseBytes were
+	// discarded
+	Truncated bool `json:"-"`
 }
 
 type DomainSearchData struct {
@@ -37,30 +43,30 @@ type DomainSearchData struct {
 }
 
 type URLInfo struct {
-	Occurrence int    `json:"occurrence"`
+	Occurrence Int    `json:"occurrence"`
 	Type       string `json:"type"`
 	URL        string `json:"H"`
 }
 
 type Stats struct {
-	TotalEmployees int      `json:"totalEmployees"`
-	TotalUsers     int      `json:"totalUsers"`
+	TotalEmployees Int      `json:"totalEmployees"`
+	TotalUsers     Int      `json:"totalUsers"`
 	EmployeesURLs  []string `json:"employees_urls"`
 	ClientsURLs    []string `json:"clients_urls"`
-	EmployeesCount []int    `json:"employees_count"`
-	ClientsCount   []int    `json:"clients_count"`
+	EmployeesCount []Int    `json:"employees_count"`
+	ClientsCount   []Int    `json:"clients_count"`
 }
 
 type Antiviruses struct {
-	Total    int         `json:"total"`
-	Found    float64     `json:"found"`
-	NotFound float64     `json:"not_found"`
-	Free     float64     `json:"free"`
+	Total    Int         `json:"total"`
+	Found    Float       `json:"found"`
+	NotFound Float       `json:"not_found"`
+	Free     Float       `json:"free"`
 	List     []AVProduct `json:"list"`
 }
 
 type AVProduct struct {
-	Count int    `json:"count"`
+	Count Int    `json:"count"`
 	Name  string `json:"name"`
 }
 
@@ -69,7 +75,7 @@ type Application struct {
 }
 
 type PasswordStats struct {
-	TotalPass int         `json:"totalPass"`
+	TotalPass Int         `json:"totalPass"`
 	HasStats  bool        `json:"has_stats"`
 	TooWeak   PasswordBin `json:"too_weak"`
 	Weak      PasswordBin `json:"weak"`
@@ -78,18 +84,18 @@ type PasswordStats struct {
 }
 
 type PasswordBin struct {
-	Qty  int     `json:"qty"`
-	Perc float64 `json:"perc"`
+	Qty  Int   `json:"qty"`
+	Perc Float `json:"perc"`
 }
 
 type DomainOccurrence struct {
-	Occurrence int     `json:"occurrence"`
+	Occurrence Int     `json:"occurrence"`
 	Domain     *string `json:"domain"` // pointer to handle nulls
 }
 
 func (c *Client) SearchByDomain(ctx context.Context, domain string) (DomainSearchResponse, error) {
-	// Build full URL using BaseURL constant
-	endpoint, err := url.Parse(BaseURL)
+	// Build full URL using the client's base URL
+	endpoint, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return DomainSearchResponse{}, err
 	}
@@ -104,14 +110,25 @@ func (c *Client) SearchByDomain(ctx context.Context, domain string) (DomainSearc
 
 	// Create the request function for retry logic
 	requestFunc := func() (*resty.Response, error) {
-		return c.Resty.R().
+		// The body is decoded as it is read rather than buffered by resty
+		resp, err := c.Resty.R().
+			SetContext(ctx).
 			SetHeader("Accept", "application/json").
-			SetResult(&result).
+			SetDoNotParseResponse(true).
 			Get(endpoint.String())
+		if err != nil {
+			return resp, err
+		}
+		defer resp.Body.Close()
+		if !resp.IsSuccess() {
+			return resp, nil
+		}
+		result = DomainSearchResponse{}
+		return resp, c.decodeStream(ctx, "search-by-domain", resp.Body, &result)
 	}
 
 	// Execute with client's default retry settings
-	resp, err := c.executeWithRetryDefault(requestFunc)
+	resp, err := c.executeWithRetryDefault(ctx, requestFunc)
 	if err != nil {
 		plugin.Logger(ctx).Error("Domain search failed", "domain", domain, "error", err)
 		return result, err
@@ -125,3 +142,19 @@ func (c *Client) SearchByDomain(ctx context.Context, domain string) (DomainSearc
 
 	return result, nil
 }
+
+func (r *DomainSearchResponse) setRaw(body json.RawMessage) {
+	r.Raw = body
+}
+
+func (r *DomainSearchResponse) urlLists() map[string]*[]URLInfo {
+	return map[string]*[]URLInfo{
+		"data.employees_urls": &r.Data.EmployeesURLs,
+		"data.clients_urls":   &r.Data.ClientsURLs,
+		"data.all_urls":       &r.Data.AllURLs,
+	}
+}
+
+func (r *DomainSearchResponse) setTruncated(truncated bool) {
+	r.Truncated = truncated
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/url"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
@@ -12,14 +13,16 @@ import (
 type EmailSearchResponse struct {
 	Message                string         `json:"message"`
 	Stealers               []EmailStealer `json:"stealers"`
-	TotalCorporateServices int            `json:"total_corporate_services"`
-	TotalUserServices      int            `json:"total_user_services"`
+	TotalCorporateServices Int            `json:"total_corporate_services"`
+	TotalUserServices      Int            `json:"total_user_services"`
+	// Raw is the undecoded response
+	Raw json.RawMessage `json:"-"`
 }
 
 // EmailStealer contains information about each stealer compromise.
 type EmailStealer struct {
-	TotalCorporateServices int      `json:"total_corporate_services"`
-	TotalUserServices      int      `json:"total_user_services"`
+	TotalCorporateServices Int      `json:"total_corporate_services"`
+	TotalUserServices      Int      `json:"total_user_services"`
 	DateCompromised        string   `json:"date_compromised"`
 	ComputerName           string   `json:"computer_name"`
 	OperatingSystem        string   `json:"operating_system"`
@@ -28,11 +31,13 @@ type EmailStealer struct {
 	IP                     string   `json:"ip"`
 	TopPasswords           []string `json:"top_passwords"`
 	TopLogins              []string `json:"top_logins"`
+	// Raw is the undecoded stealer object
+	Raw json.RawMessage `json:"-"`
 }
 
 func (c *Client) SearchByEmail(ctx context.Context, email string) (EmailSearchResponse, error) {
-	// Build full URL using BaseURL constant
-	endpoint, err := url.Parse(BaseURL)
+	// Build full URL using the client's base URL
+	endpoint, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return EmailSearchResponse{}, err
 	}
@@ -47,14 +52,19 @@ func (c *Client) SearchByEmail(ctx context.Context, email string) (EmailSearchRe
 
 	// Create the request function for retry logic
 	requestFunc := func() (*resty.Response, error) {
-		return c.Resty.R().
+		resp, err := c.Resty.R().
+			SetContext(ctx).
 			SetHeader("Accept", "application/json").
-			SetResult(&result).
 			Get(endpoint.String())
+		if err != nil || !resp.IsSuccess() {
+			return resp, err
+		}
+		result = EmailSearchResponse{}
+		return resp, c.decode(ctx, "search-by-email", resp.Bytes(), &result)
 	}
 
 	// Execute with client's default retry settings
-	resp, err := c.executeWithRetryDefault(requestFunc)
+	resp, err := c.executeWithRetryDefault(ctx, requestFunc)
 	if err != nil {
 		plugin.Logger(ctx).Error("Email search failed", "email", email, "error", err)
 		return result, err
@@ -67,3 +77,10 @@ func (c *Client) SearchByEmail(ctx context.Context, email string) (EmailSearchRe
 
 	return result, nil
 }
+
+func (r *EmailSearchResponse) setRaw(body json.RawMessage) {
+	r.Raw = body
+	for i, raw := range stealerObjects(body, len(r.Stealers)) {
+		r.Stealers[i].Raw = raw
+	}
+}
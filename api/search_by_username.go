@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/url"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
@@ -12,14 +13,16 @@ import (
 type UsernameSearchResponse struct {
 	Message                string            `json:"message"`
 	Stealers               []UsernameStealer `json:"stealers"`
-	TotalCorporateServices int               `json:"total_corporate_services"`
-	TotalUserServices      int               `json:"total_user_services"`
+	TotalCorporateServices Int               `json:"total_corporate_services"`
+	TotalUserServices      Int               `json:"total_user_services"`
+	// Raw is the undecoded response
+	Raw json.RawMessage `json:"-"`
 }
 
 // UsernameStealer contains details about each compromise event for the username.
 type UsernameStealer struct {
-	TotalCorporateServices int      `json:"total_corporate_services"`
-	TotalUserServices      int      `json:"total_user_services"`
+	TotalCorporateServices Int      `json:"total_corporate_services"`
+	TotalUserServices      Int      `json:"total_user_services"`
 	DateCompromised        string   `json:"date_compromised"`
 	StealerFamily          string   `json:"stealer_family"`
 	ComputerName           string   `json:"computer_name"`
@@ -29,11 +32,13 @@ type UsernameStealer struct {
 	IP                     string   `json:"ip"`
 	TopPasswords           []string `json:"top_passwords"`
 	TopLogins              []string `json:"top_logins"`
+	// Raw is the undecoded stealer object
+	Raw json.RawMessage `json:"-"`
 }
 
 func (c *Client) SearchByUsername(ctx context.Context, username string) (UsernameSearchResponse, error) {
-	// Build full URL using BaseURL constant
-	endpoint, err := url.Parse(BaseURL)
+	// Build full URL using the client's base URL
+	endpoint, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return UsernameSearchResponse{}, err
 	}
@@ -48,14 +53,19 @@ func (c *Client) SearchByUsername(ctx context.Context, username string) (Usernam
 
 	// Create the request function for retry logic
 	requestFunc := func() (*resty.Response, error) {
-		return c.Resty.R().
+		resp, err := c.Resty.R().
+			SetContext(ctx).
 			SetHeader("Accept", "application/json").
-			SetResult(&result).
 			Get(endpoint.String())
+		if err != nil || !resp.IsSuccess() {
+			return resp, err
+		}
+		result = UsernameSearchResponse{}
+		return resp, c.decode(ctx, "search-by-username", resp.Bytes(), &result)
 	}
 
 	// Execute with client's default retry settings
-	resp, err := c.executeWithRetryDefault(requestFunc)
+	resp, err := c.executeWithRetryDefault(ctx, requestFunc)
 	if err != nil {
 		plugin.Logger(ctx).Error("username search failed", "username", username, "error", err)
 		return result, err
@@ -68,3 +78,10 @@ func (c *Client) SearchByUsername(ctx context.Context, username string) (Usernam
 
 	return result, nil
 }
+
+func (r *UsernameSearchResponse) setRaw(body json.RawMessage) {
+	r.Raw = body
+	for i, raw := range stealerObjects(body, len(r.Stealers)) {
+		r.Stealers[i].Raw = raw
+	}
+}
@@ -0,0 +1,274 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// DefaultMaxResponseBytes is the default number of bytes of a domain response
+// after which URLs are discarded.
+const DefaultMaxResponseBytes = 100 << 20
+
+// The URL lists of search-by-domain and urls-by-domain run to hundreds of
+// megabytes for large enterprises. Those responses are decoded from the body
+// as it is read, one URL at a time, instead of being buffered and then
+// unmarshaled, and the URLs past MaxResponseBytes are read and discarded.
+
+// urlListResponse is implemented by responses whose URL lists are streamed.
+type urlListResponse interface {
+	rawSetter
+	// urlLists returns the lists to stream, by path in the response
+	urlLists() map[string]*[]URLInfo
+	setTruncated(truncated bool)
+}
+
+// decodeStream decodes the response read from body into v, streaming its URL
+// lists. The rest of the response is decoded, checked for schema drift and
+// kept on v as decode does, so the raw response has no URL lists, and no
+// objects left empty without them. Keeping the lists would hold the whole
+// response in memory again. Read and syntax errors are returned as is so that
+// a truncated body is retried.
+func (c *Client) decodeStream(ctx context.Context, endpoint string, body io.Reader, v urlListResponse) error {
+	s := &responseStream{
+		client:   c,
+		dec:      json.NewDecoder(body),
+		lists:    v.urlLists(),
+		problems: map[string]struct{}{},
+	}
+	s.dec.UseNumber()
+
+	rest, err := s.readObject("")
+	if err != nil {
+		return err
+	}
+	if _, err := s.dec.Token(); err != io.EOF {
+		return fmt.Errorf("%s: unexpected data after the response", endpoint)
+	}
+
+	problems, err := c.unmarshal(rest, v, "")
+	if err != nil {
+		return err
+	}
+	if err := c.checkDrift(ctx, endpoint, append(s.problemList, problems...)); err != nil {
+		return err
+	}
+
+	if s.truncated {
+		plugin.Logger(ctx).Warn("URL lists truncated", "endpoint", endpoint, "max_response_bytes", c.MaxResponseBytes, "discarded", s.discarded)
+	}
+	v.setRaw(rest)
+	v.setTruncated(s.truncated)
+	return nil
+}
+
+type responseStream struct {
+	client *Client
+	dec    *json.Decoder
+	lists  map[string]*[]URLInfo
+
+	// problems dedupes problemList, as every URL of a list drifts the same way
+	problems    map[string]struct{}
+	problemList []string
+
+	truncated bool
+	discarded int
+}
+
+// readObject reads the object at path, streaming the URL lists in it, and
+// returns the rest of the object, or nil if only URL lists were in a nested
+// object. Values that are not objects are returned as they are, for
+// unmarshal to report, except arrays which are skipped.
+func (s *responseStream) readObject(path string) ([]byte, error) {
+	token, err := s.dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if token != json.Delim('{') {
+		if token == json.Delim('[') {
+			s.problem(fmt.Sprintf("field %s: array cannot be decoded into object", path))
+			return []byte("{}"), s.skip(token)
+		}
+		return json.Marshal(token)
+	}
+
+	var rest bytes.Buffer
+	rest.WriteByte('{')
+	lists := 0
+	for s.dec.More() {
+		token, err := s.dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := token.(string)
+		memberPath := joinPath(path, key)
+
+		if list := s.list(memberPath); list != nil {
+			if err := s.readList(memberPath, list); err != nil {
+				return nil, err
+			}
+			lists++
+			continue
+		}
+
+		var value []byte
+		if s.hasList(memberPath) {
+			value, err = s.readObject(memberPath)
+		} else {
+			var raw json.RawMessage
+			err = s.dec.Decode(&raw)
+			value = raw
+		}
+		if err != nil {
+			return nil, err
+		}
+		if value == nil {
+			continue
+		}
+
+		if rest.Len() > 1 {
+			rest.WriteByte(',')
+		}
+		encodedKey, _ := json.Marshal(key)
+		rest.Write(encodedKey)
+		rest.WriteByte(':')
+		rest.Write(value)
+	}
+	// Closing brace
+	if _, err := s.dec.Token(); err != nil {
+		return nil, err
+	}
+	if path != "" && lists > 0 && rest.Len() == 1 {
+		return nil, nil
+	}
+	rest.WriteByte('}')
+	return rest.Bytes(), nil
+}
+
+// readList reads the array of URLs at path into list, discarding the URLs
+// that start past MaxResponseBytes.
+func (s *responseStream) readList(path string, list *[]URLInfo) error {
+	token, err := s.dec.Token()
+	if err != nil {
+		return err
+	}
+	if token != json.Delim('[') {
+		if token != nil {
+			s.problem(fmt.Sprintf("field %s: %s cannot be decoded into array", path, tokenKind(token)))
+		}
+		return s.skip(token)
+	}
+
+	strict := s.client.StrictDecoding == StrictDecodingLog || s.client.StrictDecoding == StrictDecodingError
+	maxBytes := s.client.MaxResponseBytes
+	for s.dec.More() {
+		if maxBytes > 0 && s.dec.InputOffset() > maxBytes {
+			// Decoding into an empty struct reads the URL without keeping it
+			var discard struct{}
+			if err := s.dec.Decode(&discard); err != nil {
+				if _, ok := typeProblem(err, path); !ok {
+					return err
+				}
+			}
+			s.truncated = true
+			s.discarded++
+			continue
+		}
+
+		// Decoded in place, as a URL decoded into a variable escapes
+		*list = append(*list, URLInfo{})
+		url := &(*list)[len(*list)-1]
+		if strict {
+			var raw json.RawMessage
+			if err := s.dec.Decode(&raw); err != nil {
+				return err
+			}
+			problems, err := s.client.unmarshal(raw, url, path+"[]")
+			if err != nil {
+				return err
+			}
+			s.problem(problems...)
+		} else if err := s.dec.Decode(url); err != nil {
+			problem, ok := typeProblem(err, path+"[]")
+			if !ok {
+				return err
+			}
+			s.problem(problem)
+		}
+	}
+	// Closing bracket
+	_, err = s.dec.Token()
+	return err
+}
+
+// list returns the URL list streamed at path, if any.
+func (s *responseStream) list(path string) *[]URLInfo {
+	for listPath, list := range s.lists {
+		if strings.EqualFold(listPath, path) {
+			return list
+		}
+	}
+	return nil
+}
+
+// hasList reports whether a URL list is streamed below path.
+func (s *responseStream) hasList(path string) bool {
+	for listPath := range s.lists {
+		if len(listPath) > len(path) && strings.EqualFold(listPath[:len(path)+1], path+".") {
+			return true
+		}
+	}
+	return false
+}
+
+func (s *responseStream) problem(problems ...string) {
+	for _, problem := range problems {
+		if _, ok := s.problems[problem]; !ok {
+			s.problems[problem] = struct{}{}
+			s.problemList = append(s.problemList, problem)
+		}
+	}
+}
+
+// skip reads the rest of the value starting with token.
+func (s *responseStream) skip(token json.Token) error {
+	depth := 0
+	for {
+		switch token {
+		case json.Delim('{'), json.Delim('['):
+			depth++
+		case json.Delim('}'), json.Delim(']'):
+			depth--
+		}
+		if depth == 0 {
+			return nil
+		}
+		var err error
+		if token, err = s.dec.Token(); err != nil {
+			if errors.Is(err, io.EOF) {
+				return io.ErrUnexpectedEOF
+			}
+			return err
+		}
+	}
+}
+
+// tokenKind names the JSON type of a scalar or opening token, as
+// json.UnmarshalTypeError does.
+func tokenKind(token json.Token) string {
+	switch token.(type) {
+	case json.Delim:
+		return "object"
+	case bool:
+		return "bool"
+	case json.Number:
+		return "number"
+	}
+	return "string"
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+)
+
+// largeDomainResponse returns a search-by-domain response of about size
+// bytes, nearly all of it URLs, with counters both before and after the data
+// object.
+func largeDomainResponse(size int) []byte {
+	var buf bytes.Buffer
+	buf.Grow(size + 1024)
+	buf.WriteString(`{"total":"1,204","employees":37,"data":{"clients_urls":[],"employees_urls":[`)
+	for i := 0; buf.Len() < size/4; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"occurrence":%d,"type":"employee","H":"https://sso%d.example.com/login"}`, i%50+1, i)
+	}
+	buf.WriteString(`],"all_urls":[`)
+	for i := 0; buf.Len() < size; i++ {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		fmt.Fprintf(&buf, `{"occurrence":%d,"type":"client","H":"https://app%d.example.com/account/login"}`, i%50+1, i)
+	}
+	buf.WriteString(`]},"users":1247,"totalUrls":"9,999"}`)
+	return buf.Bytes()
+}
+
+func TestDecodeStreamMatchesDecode(t *testing.T) {
+	ctx := hudsonrocktest.Context()
+	client := NewClient()
+
+	for _, tt := range []struct {
+		endpoint string
+		newValue func() urlListResponse
+	}{
+		{hudsonrocktest.EndpointSearchByDomain, func() urlListResponse { return &DomainSearchResponse{} }},
+		{hudsonrocktest.EndpointURLsByDomain, func() urlListResponse { return &URLSearchResponse{} }},
+	} {
+		t.Run(tt.endpoint, func(t *testing.T) {
+			body, err := hudsonrocktest.Fixture(tt.endpoint)
+			if err != nil {
+				t.Fatal(err)
+			}
+
+			buffered, streamed := tt.newValue(), tt.newValue()
+			if err := client.decode(ctx, tt.endpoint, body, buffered); err != nil {
+				t.Fatalf("decode: %v", err)
+			}
+			if err := client.decodeStream(ctx, tt.endpoint, bytes.NewReader(body), streamed); err != nil {
+				t.Fatalf("decodeStream: %v", err)
+			}
+
+			var raw map[string]any
+			rawValue := reflect.ValueOf(streamed).Elem().FieldByName("Raw")
+			if err := json.Unmarshal(rawValue.Bytes(), &raw); err != nil {
+				t.Fatalf("Raw = %s: %v", rawValue.Bytes(), err)
+			}
+			// The data object only holds URL lists, so it is left out
+			if data, ok := raw["data"]; ok {
+				t.Errorf("Raw has data: %v", data)
+			}
+
+			// Apart from Raw, the results are the same
+			buffered.setRaw(nil)
+			streamed.setRaw(nil)
+			if !reflect.DeepEqual(buffered, streamed) {
+				t.Errorf("decodeStream = %+v\nwant %+v", streamed, buffered)
+			}
+		})
+	}
+}
+
+func TestDecodeStreamRaw(t *testing.T) {
+	ctx := hudsonrocktest.Context()
+	client := NewClient()
+
+	for _, tt := range []struct {
+		body string
+		want string
+	}{
+		{`{"total":1,"data":{"all_urls":[{"H":"https://a.example"}]}}`, `{"total":1}`},
+		{`{"total":1,"data":{"all_urls":[],"note":"kept"}}`, `{"total":1,"data":{"note":"kept"}}`},
+		{`{"total":1,"data":{}}`, `{"total":1,"data":{}}`},
+		{`{"total":1}`, `{"total":1}`},
+	} {
+		var result DomainSearchResponse
+		if err := client.decodeStream(ctx, "search-by-domain", strings.NewReader(tt.body), &result); err != nil {
+			t.Fatalf("decodeStream(%s): %v", tt.body, err)
+		}
+		if string(result.Raw) != tt.want {
+			t.Errorf("decodeStream(%s) Raw = %s, want %s", tt.body, result.Raw, tt.want)
+		}
+	}
+}
+
+func TestMaxResponseBytes(t *testing.T) {
+	body := largeDomainResponse(1 << 20)
+
+	for _, tt := range []struct {
+		maxBytes      int64
+		wantTruncated bool
+	}{
+		{maxBytes: 0},
+		{maxBytes: 2 << 20},
+		{maxBytes: 100 << 10, wantTruncated: true},
+	} {
+		t.Run(fmt.Sprint(tt.maxBytes), func(t *testing.T) {
+			client, server := newTestClient(t)
+			client.WithMaxResponseBytes(tt.maxBytes)
+			server.SetResponse(hudsonrocktest.EndpointSearchByDomain, "big.example", body)
+
+			result, err := client.SearchByDomain(hudsonrocktest.Context(), "big.example")
+			if err != nil {
+				t.Fatalf("SearchByDomain: %v", err)
+			}
+			if result.Truncated != tt.wantTruncated {
+				t.Errorf("Truncated = %t, want %t", result.Truncated, tt.wantTruncated)
+			}
+			// Counters after the data object are decoded either way
+			if result.Total != 1204 || result.Users != 1247 || result.TotalUrls != 9999 {
+				t.Errorf("counters = %d, %d, %d", result.Total, result.Users, result.TotalUrls)
+			}
+
+			urls := len(result.Data.EmployeesURLs) + len(result.Data.AllURLs)
+			full := strings.Count(string(body), `"H":`)
+			switch {
+			case tt.wantTruncated && (urls == 0 || urls >= full):
+				t.Errorf("kept %d of %d URLs", urls, full)
+			case !tt.wantTruncated && urls != full:
+				t.Errorf("kept %d URLs, want all %d", urls, full)
+			}
+		})
+	}
+}
+
+func TestDecodeStreamDrift(t *testing.T) {
+	ctx := hudsonrocktest.Context()
+	body := `{"total":1,"data":{"all_urls":[{"H":"a","rank":1},{"H":"b","occurrence":"n/a"},{"H":"c","rank":2}],"employees_urls":"none"},"extra":true}`
+
+	client, _ := NewClient().WithStrictDecoding(StrictDecodingError)
+	err := client.decodeStream(ctx, "search-by-domain", strings.NewReader(body), &DomainSearchResponse{})
+	if !errors.Is(err, ErrSchemaDrift) {
+		t.Fatalf("got error %v, want ErrSchemaDrift", err)
+	}
+	for _, want := range []string{
+		"unknown field data.all_urls[].rank",
+		"field data.all_urls[]: string cannot be decoded into api.Int",
+		"field data.employees_urls: string cannot be decoded into array",
+		"unknown field extra",
+	} {
+		if n := strings.Count(err.Error(), want); n != 1 {
+			t.Errorf("error %q contains %q %d times, want once", err, want, n)
+		}
+	}
+
+	client, _ = client.WithStrictDecoding(StrictDecodingLog)
+	var result DomainSearchResponse
+	if err := client.decodeStream(ctx, "search-by-domain", strings.NewReader(body), &result); err != nil {
+		t.Fatalf("log mode: %v", err)
+	}
+	if len(result.Data.AllURLs) != 3 || result.Data.AllURLs[2].URL != "c" {
+		t.Errorf("AllURLs = %+v", result.Data.AllURLs)
+	}
+
+	// A body cut short is not drift, so that it is retried
+	err = client.decodeStream(ctx, "search-by-domain", strings.NewReader(body[:60]), &DomainSearchResponse{})
+	if err == nil || errors.Is(err, ErrSchemaDrift) {
+		t.Errorf("truncated body: got error %v", err)
+	}
+}
+
+// BenchmarkDomainResponse compares buffering a 50 MB search-by-domain body
+// and unmarshaling it, as resty did, with streaming it. Besides allocations,
+// it reports the heap still held by a decoded response, which for a buffered
+// body includes the body itself.
+func BenchmarkDomainResponse(b *testing.B) {
+	body := largeDomainResponse(50 << 20)
+	ctx := hudsonrocktest.Context()
+
+	buffered := func(client *Client) (any, error) {
+		data, err := io.ReadAll(bytes.NewReader(body))
+		if err != nil {
+			return nil, err
+		}
+		var result DomainSearchResponse
+		return &result, client.decode(ctx, "search-by-domain", data, &result)
+	}
+	streamed := func(client *Client) (any, error) {
+		var result DomainSearchResponse
+		return &result, client.decodeStream(ctx, "search-by-domain", bytes.NewReader(body), &result)
+	}
+
+	for _, bb := range []struct {
+		name     string
+		maxBytes int64
+		decode   func(*Client) (any, error)
+	}{
+		{"buffered", 0, buffered},
+		{"streamed", 0, streamed},
+		{"streamed_10MB_limit", 10 << 20, streamed},
+	} {
+		b.Run(bb.name, func(b *testing.B) {
+			client := NewClient().WithMaxResponseBytes(bb.maxBytes)
+			b.SetBytes(int64(len(body)))
+			b.ReportAllocs()
+			for i := 0; i < b.N; i++ {
+				if _, err := bb.decode(client); err != nil {
+					b.Fatal(err)
+				}
+			}
+
+			b.StopTimer()
+			var before, after runtime.MemStats
+			runtime.GC()
+			runtime.ReadMemStats(&before)
+			result, _ := bb.decode(client)
+			runtime.GC()
+			runtime.ReadMemStats(&after)
+			runtime.KeepAlive(result)
+			b.ReportMetric(float64(int64(after.HeapAlloc)-int64(before.HeapAlloc))/(1<<20), "retained-MiB")
+		})
+	}
+}
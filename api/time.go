@@ -0,0 +1,30 @@
+package api
+
+import (
+	"strings"
+	"time"
+)
+
+// dateLayouts lists the timestamp formats returned by the Hudson Rock API for
+// compromise dates.
+var dateLayouts = []string{
+	time.RFC3339Nano,
+	"2006-01-02T15:04:05",
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// ParseDate parses a compromise date returned by the API. It returns false if
+// the value is empty or not in a known format.
+func ParseDate(value string) (time.Time, bool) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, false
+	}
+	for _, layout := range dateLayouts {
+		if t, err := time.Parse(layout, value); err == nil {
+			return t.UTC(), true
+		}
+	}
+	return time.Time{}, false
+}
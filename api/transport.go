@@ -0,0 +1,150 @@
+package api
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+)
+
+// TLS versions, as set by tls_min_version.
+var tlsVersions = map[string]uint16{
+	"1.0": tls.VersionTLS10,
+	"1.1": tls.VersionTLS11,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TransportConfig holds the proxy and TLS settings of the connection to the
+// API. The zero value keeps the defaults of Go: the proxy of the
+// HTTP_PROXY, HTTPS_PROXY and NO_PROXY environment variables, the system
+// certificate pool and TLS 1.2.
+type TransportConfig struct {
+	// ProxyURL is an http, https, socks5 or socks5h URL
+	ProxyURL string
+	// CACertFile is a PEM file of certificates trusted besides the system's,
+	// e.g. of a TLS-inspecting proxy
+	CACertFile         string
+	InsecureSkipVerify bool
+	// ClientCertFile and ClientKeyFile are the PEM certificate and key the
+	// client authenticates with, if the proxy or server asks for one
+	ClientCertFile string
+	ClientKeyFile  string
+	// TLSMinVersion is one of 1.0, 1.1, 1.2 or 1.3
+	TLSMinVersion string
+}
+
+// WithTransportConfig configures the proxy and TLS settings of the client.
+// It must be called before WithCassettes, whose transport wraps the one it
+// configures.
+func (c *Client) WithTransportConfig(config TransportConfig) (*Client, error) {
+	tlsConfig, err := config.TLSConfig()
+	if err != nil {
+		return c, err
+	}
+	return c.WithTransport(config.ProxyURL, tlsConfig)
+}
+
+// WithTransport configures the client with a proxy, if proxyURL is not empty,
+// and a TLS config built by TransportConfig.TLSConfig, which can be shared by
+// clients so that the certificate files are only loaded once. It must be
+// called before WithCassettes.
+func (c *Client) WithTransport(proxyURL string, tlsConfig *tls.Config) (*Client, error) {
+	if _, err := c.Resty.HTTPTransport(); err != nil {
+		return c, fmt.Errorf("transport config: %w", err)
+	}
+	if proxyURL != "" {
+		parsed, err := ParseProxyURL(proxyURL)
+		if err != nil {
+			return c, err
+		}
+		c.Resty.SetProxy(parsed.String())
+	}
+	c.Resty.SetTLSClientConfig(tlsConfig)
+	return c, nil
+}
+
+// TLSConfig returns the TLS config of the settings, loading the certificate
+// files.
+func (config TransportConfig) TLSConfig() (*tls.Config, error) {
+	tlsConfig := &tls.Config{InsecureSkipVerify: config.InsecureSkipVerify}
+	if config.TLSMinVersion != "" {
+		version, err := ParseTLSVersion(config.TLSMinVersion)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.MinVersion = version
+	}
+	if config.CACertFile != "" {
+		pool, err := LoadCACertPool(config.CACertFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.RootCAs = pool
+	}
+	if config.ClientCertFile != "" || config.ClientKeyFile != "" {
+		cert, err := LoadClientCertificate(config.ClientCertFile, config.ClientKeyFile)
+		if err != nil {
+			return nil, err
+		}
+		tlsConfig.Certificates = []tls.Certificate{cert}
+	}
+	return tlsConfig, nil
+}
+
+// ParseProxyURL parses an http, https, socks5 or socks5h proxy URL.
+func ParseProxyURL(rawURL string) (*url.URL, error) {
+	proxyURL, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("invalid proxy URL %q: %w", rawURL, err)
+	}
+	switch proxyURL.Scheme {
+	case "http", "https", "socks5", "socks5h":
+	default:
+		return nil, fmt.Errorf("invalid proxy URL %q, the scheme must be http, https, socks5 or socks5h", rawURL)
+	}
+	if proxyURL.Host == "" {
+		return nil, fmt.Errorf("invalid proxy URL %q, it has no host", rawURL)
+	}
+	return proxyURL, nil
+}
+
+// ParseTLSVersion returns the TLS version named 1.0, 1.1, 1.2 or 1.3.
+func ParseTLSVersion(version string) (uint16, error) {
+	if v, ok := tlsVersions[version]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("invalid TLS version %q, must be 1.0, 1.1, 1.2 or 1.3", version)
+}
+
+// LoadCACertPool returns the system certificate pool with the PEM
+// certificates of file added.
+func LoadCACertPool(file string) (*x509.CertPool, error) {
+	pem, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("read CA certificates: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(pem) {
+		return nil, fmt.Errorf("no PEM certificate found in %s", file)
+	}
+	return pool, nil
+}
+
+// LoadClientCertificate loads the PEM client certificate and key of the
+// client, which must be set together.
+func LoadClientCertificate(certFile, keyFile string) (tls.Certificate, error) {
+	if certFile == "" || keyFile == "" {
+		return tls.Certificate{}, errors.New("a client certificate needs both a certificate and a key file")
+	}
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("load client certificate: %w", err)
+	}
+	return cert, nil
+}
@@ -2,6 +2,7 @@ package api
 
 import (
 	"context"
+	"encoding/json"
 	"net/url"
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
@@ -12,6 +13,11 @@ import (
 type URLSearchResponse struct {
 	Message string       `json:"message"`
 	Data    URLDataGroup `json:"data"`
+	// Raw is the undecoded response, without the URL lists
+	Raw json.RawMessage `json:"-"`
+	// Truncated is set if URLs past the client's MaxResponseBytes were
+	// discarded
+	Truncated bool `json:"-"`
 }
 
 // URLDataGroup holds lists of URLs for employees and clients.
@@ -21,8 +27,8 @@ type URLDataGroup struct {
 }
 
 func (c *Client) UrlByDomain(ctx context.Context, domain string) (URLSearchResponse, error) {
-	// Build full URL using BaseURL constant
-	endpoint, err := url.Parse(BaseURL)
+	// Build full URL using the client's base URL
+	endpoint, err := url.Parse(c.BaseURL)
 	if err != nil {
 		return URLSearchResponse{}, err
 	}
@@ -37,14 +43,25 @@ func (c *Client) UrlByDomain(ctx context.Context, domain string) (URLSearchRespo
 
 	// Create the request function for retry logic
 	requestFunc := func() (*resty.Response, error) {
-		return c.Resty.R().
+		// The body is decoded as it is read rather than buffered by resty
+		resp, err := c.Resty.R().
+			SetContext(ctx).
 			SetHeader("Accept", "application/json").
-			SetResult(&result).
+			SetDoNotParseResponse(true).
 			Get(endpoint.String())
+		if err != nil {
+			return resp, err
+		}
+		defer resp.Body.Close()
+		if !resp.IsSuccess() {
+			return resp, nil
+		}
+		result = URLSearchResponse{}
+		return resp, c.decodeStream(ctx, "urls-by-domain", resp.Body, &result)
 	}
 
 	// Execute with client's default retry settings
-	resp, err := c.executeWithRetryDefault(requestFunc)
+	resp, err := c.executeWithRetryDefault(ctx, requestFunc)
 	if err != nil {
 		plugin.Logger(ctx).Error("Domain search failed", "domain", domain, "error", err)
 		return result, err
@@ -57,3 +74,18 @@ func (c *Client) UrlByDomain(ctx context.Context, domain string) (URLSearchRespo
 
 	return result, nil
 }
+
+func (r *URLSearchResponse) setRaw(body json.RawMessage) {
+	r.Raw = body
+}
+
+func (r *URLSearchResponse) urlLists() map[string]*[]URLInfo {
+	return map[string]*[]URLInfo{
+		"data.employees_urls": &r.Data.EmployeesURLs,
+		"data.clients_urls":   &r.Data.ClientsURLs,
+	}
+}
+
+func (r *URLSearchResponse) setTruncated(truncated bool) {
+	r.Truncated = truncated
+}
@@ -2,23 +2,150 @@ package hudsonrock
 
 import (
 	"context"
-	"time"
 
 	"github.com/turbot/steampipe-plugin-hudsonrock/api"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 )
 
-func NewClient(ctx context.Context, d *plugin.QueryData) *api.Client {
+// fanOutTags tags the list calls of the tables making several API calls per
+// query, which the hudsonrock_fan_out rate limiter applies to.
+var fanOutTags = map[string]string{"fan_out": "true"}
+
+// NewClient returns an API client configured by the query's connection. The
+// connection config is validated by checkedConnection, once per connection,
+// as the SDK only validates it when the first connection loads.
+func NewClient(ctx context.Context, d *plugin.QueryData) (*api.Client, error) {
+	tlsConfig, err := checkedConnection(ctx, d.Connection)
+	if err != nil {
+		return nil, err
+	}
 	config := GetConfig(d.Connection)
 
 	client := api.NewClient()
 
+	if config.BaseURL != nil {
+		client.WithBaseURL(*config.BaseURL)
+	}
 	if config.MaxRetries != nil {
 		client.WithMaxRetries(*config.MaxRetries)
 	}
+	if config.MaxResponseBytes != nil {
+		client.WithMaxResponseBytes(*config.MaxResponseBytes)
+	}
+	// The timing settings have been validated
 	if config.MinDelay != nil {
-		client.WithMinDelay(time.Duration(*config.MinDelay) * time.Second)
+		minDelay, _ := parseDuration(*config.MinDelay)
+		client.WithMinDelay(minDelay)
+	}
+	if config.MaxDelay != nil {
+		maxDelay, _ := parseDuration(*config.MaxDelay)
+		client.WithMaxDelay(maxDelay)
+	}
+	if config.RequestTimeout != nil {
+		timeout, _ := parseDuration(*config.RequestTimeout)
+		client.WithRequestTimeout(timeout)
+	}
+	if config.BackoffStrategy != nil {
+		strategy, err := api.NewBackoffStrategy(*config.BackoffStrategy)
+		if err != nil {
+			return nil, err
+		}
+		client.WithBackoffStrategy(strategy)
+	}
+	client.WithRateLimiter(queryRateLimiter{d})
+
+	if config.StrictDecoding != nil {
+		if _, err := client.WithStrictDecoding(*config.StrictDecoding); err != nil {
+			return nil, err
+		}
+	}
+
+	// The transport must be configured before the cassettes wrap it
+	proxyURL := ""
+	if config.ProxyURL != nil {
+		proxyURL = *config.ProxyURL
+	}
+	if _, err := client.WithTransport(proxyURL, tlsConfig); err != nil {
+		return nil, err
 	}
 
-	return client
+	if mode, dir := cassetteConfig(config); mode != "" {
+		if expanded, err := expandPath(dir); err == nil {
+			dir = expanded
+		}
+		if _, err := client.WithCassettes(mode, dir); err != nil {
+			return nil, err
+		}
+	}
+
+	return client, nil
+}
+
+// queryRateLimiter waits on the rate limiters of the query's list call, so
+// that every API call of a fan-out table is throttled, not only the list call.
+// The limiters are the plugin's RateLimiters and the limiter blocks of the
+// plugin config that match the call.
+type queryRateLimiter struct {
+	d *plugin.QueryData
+}
+
+func (l queryRateLimiter) Wait(ctx context.Context) error {
+	l.d.WaitForListRateLimit(ctx)
+	return ctx.Err()
+}
+
+// cassetteConfig returns the cassette mode and directory set on the
+// connection, if any. replay_dir wins over record_dir so that a replay never
+// reaches the live API.
+func cassetteConfig(config HudsonRockConfig) (string, string) {
+	if config.ReplayDir != nil && *config.ReplayDir != "" {
+		return api.CassetteReplay, *config.ReplayDir
+	}
+	if config.RecordDir != nil && *config.RecordDir != "" {
+		return api.CassetteRecord, *config.RecordDir
+	}
+	return "", ""
+}
+
+// transportConfig returns the proxy and TLS settings of the connection, with
+// the paths of the certificate files expanded.
+func transportConfig(config HudsonRockConfig) (api.TransportConfig, error) {
+	var transport api.TransportConfig
+	if config.ProxyURL != nil {
+		transport.ProxyURL = *config.ProxyURL
+	}
+	if config.InsecureSkipVerify != nil {
+		transport.InsecureSkipVerify = *config.InsecureSkipVerify
+	}
+	if config.TLSMinVersion != nil {
+		transport.TLSMinVersion = *config.TLSMinVersion
+	}
+	for _, file := range []struct {
+		value  *string
+		target *string
+	}{
+		{config.CACertFile, &transport.CACertFile},
+		{config.ClientCertFile, &transport.ClientCertFile},
+		{config.ClientKeyFile, &transport.ClientKeyFile},
+	} {
+		if file.value == nil || *file.value == "" {
+			continue
+		}
+		path, err := expandPath(*file.value)
+		if err != nil {
+			return transport, err
+		}
+		*file.target = path
+	}
+	return transport, nil
+}
+
+// warnInsecureSkipVerify logs that the connection does not verify the
+// certificates of the API, which is only meant to debug a proxy.
+func warnInsecureSkipVerify(ctx context.Context, connection *plugin.Connection) {
+	name := ""
+	if connection != nil {
+		name = connection.Name
+	}
+	plugin.Logger(ctx).Warn("insecure_skip_verify is set: TLS certificates are NOT verified and API traffic can be read and altered by anyone on the network path; do not leave it set", "connection", name)
 }
@@ -0,0 +1,37 @@
+package hudsonrock
+
+import (
+	"context"
+	"sync"
+)
+
+const defaultMaxConcurrency = 5
+
+// forEachConcurrently calls fn for every item, running at most limit calls at
+// a time, and waits for them to finish. Items not yet started when ctx is
+// cancelled are skipped.
+func forEachConcurrently[T any](ctx context.Context, items []T, limit int, fn func(ctx context.Context, i int, item T)) {
+	if limit < 1 {
+		limit = 1
+	}
+
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, limit)
+	for i, item := range items {
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			wg.Wait()
+			return
+		}
+		wg.Add(1)
+		go func() {
+			defer func() {
+				<-sem
+				wg.Done()
+			}()
+			fn(ctx, i, item)
+		}()
+	}
+	wg.Wait()
+}
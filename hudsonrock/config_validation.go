@@ -0,0 +1,328 @@
+package hudsonrock
+
+import (
+	"context"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
+	"github.com/hashicorp/hcl/v2/hclsyntax"
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// tableMapFunc validates the connection config when the connection loads,
+// so that a mistake fails the connection instead of the first query using
+// it. For a static schema plugin, the SDK calls it for every connection that
+// loads until one succeeds, and reuses that table map for later connections,
+// so NewClient validates those on their first query.
+func tableMapFunc(tables map[string]*plugin.Table) plugin.TableMapFunc {
+	return func(ctx context.Context, d *plugin.TableMapData) (map[string]*plugin.Table, error) {
+		if _, err := checkedConnection(ctx, d.Connection); err != nil {
+			return nil, err
+		}
+		return tables, nil
+	}
+}
+
+// connectionCheck is a successfully validated connection config, with the
+// TLS config loaded from its certificate files.
+type connectionCheck struct {
+	// configKey identifies the config that was validated
+	configKey string
+	tlsConfig *tls.Config
+}
+
+var (
+	connectionChecksMu sync.Mutex
+	connectionChecks   = map[string]*connectionCheck{}
+)
+
+// checkedConnection validates the connection config once per connection and
+// config, so that the files it names are read, and the insecure_skip_verify
+// warning logged, when the config loads or changes rather than on every
+// query. It returns the TLS config of the connection. Failures are not
+// cached, so that fixing a file named by the config is picked up
+// by the next query.
+func checkedConnection(ctx context.Context, connection *plugin.Connection) (*tls.Config, error) {
+	config := GetConfig(connection)
+	name := ""
+	if connection != nil {
+		name = connection.Name
+	}
+	key := connectionConfigKey(config)
+
+	connectionChecksMu.Lock()
+	defer connectionChecksMu.Unlock()
+
+	if check, ok := connectionChecks[name]; ok && check.configKey == key {
+		return check.tlsConfig, nil
+	}
+	delete(connectionChecks, name)
+
+	if err := validateConfig(config); err != nil {
+		return nil, err
+	}
+	// The settings have been validated, so this only loads the files
+	transport, _ := transportConfig(config)
+	tlsConfig, err := transport.TLSConfig()
+	if err != nil {
+		return nil, err
+	}
+	if transport.InsecureSkipVerify {
+		warnInsecureSkipVerify(ctx, connection)
+	}
+	connectionChecks[name] = &connectionCheck{configKey: key, tlsConfig: tlsConfig}
+	return tlsConfig, nil
+}
+
+// connectionConfigKey returns the settings of config as JSON, without the
+// parsed HCL body.
+func connectionConfigKey(config HudsonRockConfig) string {
+	config.Body = nil
+	key, _ := json.Marshal(config)
+	return string(key)
+}
+
+// validateConfig checks every setting of the connection config and returns
+// all the problems found, each with the position of the setting in the
+// connection block when it is known.
+func validateConfig(config HudsonRockConfig) error {
+	v := &configValidator{}
+	if body, ok := config.Body.(*hclsyntax.Body); ok {
+		v.body = body
+	}
+
+	if config.BaseURL != nil {
+		if u, err := url.Parse(*config.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			v.attribute("base_url", "must be an http or https URL, got %q", *config.BaseURL)
+		}
+	}
+	if config.MaxRetries != nil && *config.MaxRetries < 1 {
+		v.attribute("max_retries", "must be greater than or equal to 1, got %d", *config.MaxRetries)
+	}
+	if config.MaxResponseBytes != nil && *config.MaxResponseBytes < 0 {
+		v.attribute("max_response_bytes", "must be greater than or equal to 0, got %d", *config.MaxResponseBytes)
+	}
+
+	minDelay := v.duration("min_delay", config.MinDelay, false)
+	maxDelay := v.duration("max_delay", config.MaxDelay, true)
+	if minDelay != nil && maxDelay != nil && *maxDelay < *minDelay {
+		v.attribute("max_delay", "must not be less than min_delay")
+	}
+	v.duration("request_timeout", config.RequestTimeout, true)
+
+	if config.BackoffStrategy != nil {
+		if _, err := api.NewBackoffStrategy(*config.BackoffStrategy); err != nil {
+			v.attribute("backoff_strategy", "%s", err)
+		}
+	}
+	if config.StrictDecoding != nil {
+		if _, err := api.ParseStrictDecoding(*config.StrictDecoding); err != nil {
+			v.attribute("strict_decoding", "%s", err)
+		}
+	}
+
+	v.transport(config)
+
+	for _, path := range []struct {
+		name  string
+		value *string
+	}{{"record_dir", config.RecordDir}, {"replay_dir", config.ReplayDir}, {"snapshot_path", config.SnapshotPath}} {
+		if path.value != nil {
+			if _, err := expandPath(*path.value); err != nil {
+				v.attribute(path.name, "%s", err)
+			}
+		}
+	}
+	if config.VendorsFile != nil {
+		if _, err := loadVendors(HudsonRockConfig{VendorsFile: config.VendorsFile}); err != nil {
+			v.attribute("vendors_file", "%s", err)
+		}
+	}
+
+	if config.Since != nil && strings.TrimSpace(*config.Since) != "" {
+		if _, err := parseSince(*config.Since, timeNow()); err != nil {
+			v.attribute("since", "%s", err)
+		}
+	}
+	if _, err := parseDeviceMatchRules(config.DeviceMatchRules); err != nil {
+		v.attribute("device_match_rules", "%s", err)
+	}
+
+	if config.Recency != nil {
+		if _, err := newRecencyPolicy(HudsonRockConfig{Recency: config.Recency}, timeNow()); err != nil {
+			v.block("recency", 0, "%s", err)
+		}
+	}
+	if config.RiskModel != nil {
+		if _, err := newRiskModel(config.RiskModel); err != nil {
+			v.block("risk_model", 0, "%s", err)
+		}
+	}
+	if config.Watchlist != nil && config.Watchlist.MaxConcurrency != nil && *config.Watchlist.MaxConcurrency < 1 {
+		v.blockAttribute("watchlist", 0, "max_concurrency", "watchlist max_concurrency must be greater than or equal to 1, got %d", *config.Watchlist.MaxConcurrency)
+	}
+
+	names := map[string]bool{}
+	for i, policy := range config.PasswordPolicies {
+		if names[policy.Name] {
+			v.block("password_policy", i, "password_policy %q is defined more than once", policy.Name)
+		}
+		names[policy.Name] = true
+		if _, err := newPasswordPolicies([]PasswordPolicyConfig{policy}); err != nil {
+			v.block("password_policy", i, "%s", err)
+		}
+	}
+
+	return v.err()
+}
+
+// transport checks the proxy and TLS settings, loading the certificate
+// files.
+func (v *configValidator) transport(config HudsonRockConfig) {
+	if config.ProxyURL != nil {
+		if _, err := api.ParseProxyURL(*config.ProxyURL); err != nil {
+			v.attribute("proxy_url", "%s", err)
+		}
+	}
+	if config.TLSMinVersion != nil {
+		if _, err := api.ParseTLSVersion(*config.TLSMinVersion); err != nil {
+			v.attribute("tls_min_version", "%s", err)
+		}
+	}
+
+	transport, err := transportConfig(config)
+	if err != nil {
+		v.problems = append(v.problems, err.Error())
+		return
+	}
+	if transport.CACertFile != "" {
+		if _, err := api.LoadCACertPool(transport.CACertFile); err != nil {
+			v.attribute("ca_cert_file", "%s", err)
+		}
+	}
+	switch {
+	case transport.ClientCertFile != "" && transport.ClientKeyFile == "":
+		v.attribute("client_cert_file", "client_key_file must be set too")
+	case transport.ClientCertFile == "" && transport.ClientKeyFile != "":
+		v.attribute("client_key_file", "client_cert_file must be set too")
+	case transport.ClientCertFile != "":
+		if _, err := api.LoadClientCertificate(transport.ClientCertFile, transport.ClientKeyFile); err != nil {
+			v.attribute("client_cert_file", "%s", err)
+		}
+	}
+}
+
+// configValidator collects the problems of a connection config. body is nil
+// if the config was not written in HCL native syntax, e.g. in JSON, in which
+// case problems have no position.
+type configValidator struct {
+	body     *hclsyntax.Body
+	problems []string
+}
+
+// duration parses the timing setting name, if set, and reports it if it is
+// invalid, negative, or zero when positive is set.
+func (v *configValidator) duration(name string, value *string, positive bool) *time.Duration {
+	if value == nil {
+		return nil
+	}
+	d, err := parseDuration(*value)
+	switch {
+	case err != nil:
+		v.attribute(name, "%s", err)
+		return nil
+	case positive && d <= 0:
+		v.attribute(name, "must be greater than 0, got %s", d)
+		return nil
+	case d < 0:
+		v.attribute(name, "must be greater than or equal to 0, got %s", d)
+		return nil
+	}
+	return &d
+}
+
+// attribute reports a problem with the top-level setting name. The problem
+// is prefixed with the name unless it already starts with it.
+func (v *configValidator) attribute(name, format string, args ...any) {
+	var subject *hcl.Range
+	if v.body != nil {
+		if attribute, ok := v.body.Attributes[name]; ok {
+			subject = attribute.Expr.Range().Ptr()
+		}
+	}
+	if problem := fmt.Sprintf(format, args...); !strings.HasPrefix(problem, name) {
+		format, args = "%s: %s", []any{name, problem}
+	}
+	v.report(subject, format, args...)
+}
+
+// block reports a problem with the index-th block of type blockType.
+func (v *configValidator) block(blockType string, index int, format string, args ...any) {
+	var subject *hcl.Range
+	if block := v.findBlock(blockType, index); block != nil {
+		subject = block.DefRange().Ptr()
+	}
+	v.report(subject, format, args...)
+}
+
+// blockAttribute reports a problem with the setting name of the index-th
+// block of type blockType.
+func (v *configValidator) blockAttribute(blockType string, index int, name, format string, args ...any) {
+	var subject *hcl.Range
+	if block := v.findBlock(blockType, index); block != nil {
+		subject = block.DefRange().Ptr()
+		if attribute, ok := block.Body.Attributes[name]; ok {
+			subject = attribute.Expr.Range().Ptr()
+		}
+	}
+	v.report(subject, format, args...)
+}
+
+func (v *configValidator) findBlock(blockType string, index int) *hclsyntax.Block {
+	if v.body == nil {
+		return nil
+	}
+	for _, block := range v.body.Blocks {
+		if block.Type != blockType {
+			continue
+		}
+		if index == 0 {
+			return block
+		}
+		index--
+	}
+	return nil
+}
+
+func (v *configValidator) report(subject *hcl.Range, format string, args ...any) {
+	problem := fmt.Sprintf(format, args...)
+	if subject != nil {
+		// The SDK parses the config from a zero position, which makes lines
+		// 0-based, and columns too on the first line only, as the scanner
+		// restarts them at 1 after a newline
+		line, column := subject.Start.Line, subject.Start.Column
+		if v.body.SrcRange.Start.Line == 0 {
+			if line == 0 {
+				column++
+			}
+			line++
+		}
+		// Positions are relative to the connection block, not to the file
+		problem += fmt.Sprintf(" (line %d of the connection block, column %d)", line, column)
+	}
+	v.problems = append(v.problems, problem)
+}
+
+func (v *configValidator) err() error {
+	if len(v.problems) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid connection config: %s", strings.Join(v.problems, "; "))
+}
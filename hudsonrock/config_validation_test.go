@@ -0,0 +1,194 @@
+package hudsonrock
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func TestParseDuration(t *testing.T) {
+	tests := []struct {
+		value string
+		want  time.Duration
+	}{
+		{value: "250ms", want: 250 * time.Millisecond},
+		{value: "2s", want: 2 * time.Second},
+		{value: "1m30s", want: 90 * time.Second},
+		{value: "1", want: time.Second},
+		{value: "0.5", want: 500 * time.Millisecond},
+		{value: " 300 ", want: 5 * time.Minute},
+		{value: "0", want: 0},
+	}
+	for _, tt := range tests {
+		got, err := parseDuration(tt.value)
+		if err != nil {
+			t.Errorf("parseDuration(%q): %v", tt.value, err)
+		} else if got != tt.want {
+			t.Errorf("parseDuration(%q) = %s, want %s", tt.value, got, tt.want)
+		}
+	}
+	for _, value := range []string{"", "2x", "ms", "1e300"} {
+		if _, err := parseDuration(value); err == nil {
+			t.Errorf("parseDuration(%q) succeeded", value)
+		}
+	}
+}
+
+func TestConnectionConfigValidation(t *testing.T) {
+	tests := []struct {
+		name   string
+		config string
+		// want are the problems the connection fails with, in order
+		want []string
+	}{
+		{
+			name: "valid",
+			config: `min_delay = "250ms"
+max_delay = 60
+request_timeout = "10s"
+backoff_strategy = "full_jitter"
+strict_decoding = "log"`,
+		},
+		{
+			name: "settings",
+			config: `max_retries = 0
+min_delay = "2x"
+max_delay = "-1s"
+request_timeout = "0s"
+base_url = "ftp://example.com"`,
+			want: []string{
+				`base_url: must be an http or https URL, got "ftp://example.com" (line 5 of the connection block, column 12)`,
+				`max_retries: must be greater than or equal to 1, got 0 (line 1 of the connection block, column 15)`,
+				`min_delay: invalid duration "2x", must be a number of seconds or a duration such as 250ms or 2s (line 2 of the connection block, column 13)`,
+				`max_delay: must be greater than 0, got -1s (line 3 of the connection block, column 13)`,
+				`request_timeout: must be greater than 0, got 0s (line 4 of the connection block, column 19)`,
+			},
+		},
+		{
+			name: "delays",
+			config: `min_delay = "10s"
+max_delay = "1s"
+backoff_strategy = "linear"`,
+			want: []string{
+				`max_delay: must not be less than min_delay (line 2 of the connection block, column 13)`,
+				`backoff_strategy: invalid backoff strategy "linear"`,
+			},
+		},
+		{
+			name: "transport",
+			config: `proxy_url = "ftp://proxy.example.com"
+tls_min_version = "1.4"
+ca_cert_file = "/nonexistent/ca.pem"
+client_cert_file = "/nonexistent/client.pem"
+insecure_skip_verify = true`,
+			want: []string{
+				`proxy_url: invalid proxy URL "ftp://proxy.example.com", the scheme must be http, https, socks5 or socks5h (line 1 of the connection block, column 13)`,
+				`tls_min_version: invalid TLS version "1.4", must be 1.0, 1.1, 1.2 or 1.3 (line 2 of the connection block, column 19)`,
+				`ca_cert_file: read CA certificates: open /nonexistent/ca.pem: no such file or directory (line 3 of the connection block, column 16)`,
+				`client_cert_file: client_key_file must be set too (line 4 of the connection block, column 20)`,
+			},
+		},
+		{
+			name: "blocks",
+			config: `watchlist {
+  max_concurrency = 0
+}`,
+			want: []string{
+				`watchlist max_concurrency must be greater than or equal to 1, got 0 (line 2 of the connection block, column 21)`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			server := plugin.Server(&plugin.ServeOpts{PluginFunc: Plugin})
+			_, err := server.SetAllConnectionConfigs(&proto.SetAllConnectionConfigsRequest{
+				Configs: []*proto.ConnectionConfig{{
+					Connection: testConnection,
+					Plugin:     pluginName,
+					Config:     tt.config,
+				}},
+				MaxCacheSizeMb: -1,
+			})
+			if len(tt.want) == 0 {
+				if err != nil {
+					t.Fatalf("SetAllConnectionConfigs: %v", err)
+				}
+				return
+			}
+			if err == nil {
+				t.Fatal("the connection loaded")
+			}
+			last := 0
+			for _, want := range tt.want {
+				i := strings.Index(err.Error(), want)
+				if i < last {
+					t.Errorf("error %q does not contain %q after the previous problem", err, want)
+					continue
+				}
+				last = i
+			}
+		})
+	}
+}
+
+func TestCheckedConnectionIsMemoized(t *testing.T) {
+	ctx := hudsonrocktest.Context()
+	dir := t.TempDir()
+	vendorsFile := filepath.Join(dir, "vendors.csv")
+	if err := os.WriteFile(vendorsFile, []byte("domain\nacme.example\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	connection := &plugin.Connection{Name: "memoized", Config: HudsonRockConfig{VendorsFile: &vendorsFile}}
+	t.Cleanup(func() {
+		connectionChecksMu.Lock()
+		defer connectionChecksMu.Unlock()
+		delete(connectionChecks, connection.Name)
+	})
+
+	if _, err := checkedConnection(ctx, connection); err != nil {
+		t.Fatalf("checkedConnection: %v", err)
+	}
+	// The files of a validated config are not read again
+	if err := os.Remove(vendorsFile); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkedConnection(ctx, connection); err != nil {
+		t.Errorf("checkedConnection with the same config: %v", err)
+	}
+
+	// A changed config is validated again
+	maxRetries := 2
+	connection.Config = HudsonRockConfig{VendorsFile: &vendorsFile, MaxRetries: &maxRetries}
+	if _, err := checkedConnection(ctx, connection); err == nil || !strings.Contains(err.Error(), "vendors_file") {
+		t.Errorf("checkedConnection with a changed config = %v, want a vendors_file error", err)
+	}
+}
+
+func TestCheckedConnectionRetriesFailures(t *testing.T) {
+	ctx := hudsonrocktest.Context()
+	vendorsFile := filepath.Join(t.TempDir(), "vendors.csv")
+	connection := &plugin.Connection{Name: "retried", Config: HudsonRockConfig{VendorsFile: &vendorsFile}}
+	t.Cleanup(func() {
+		connectionChecksMu.Lock()
+		defer connectionChecksMu.Unlock()
+		delete(connectionChecks, connection.Name)
+	})
+
+	if _, err := checkedConnection(ctx, connection); err == nil {
+		t.Fatal("checkedConnection with a missing vendors_file succeeded")
+	}
+	// Creating the missing file fixes the connection without changing its
+	// config
+	if err := os.WriteFile(vendorsFile, []byte("domain\nacme.example\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := checkedConnection(ctx, connection); err != nil {
+		t.Errorf("checkedConnection after creating vendors_file: %v", err)
+	}
+}
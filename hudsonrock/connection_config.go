@@ -1,12 +1,88 @@
 package hudsonrock
 
 import (
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/hashicorp/hcl/v2"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 )
 
 type HudsonRockConfig struct {
-	MaxRetries *int   `hcl:"max_retries,optional"`
-	MinDelay   *int64 `hcl:"min_delay,optional"`
+	BaseURL            *string                `hcl:"base_url,optional"`
+	MaxRetries         *int                   `hcl:"max_retries,optional"`
+	MaxResponseBytes   *int64                 `hcl:"max_response_bytes,optional"`
+	MinDelay           *string                `hcl:"min_delay,optional"`
+	MaxDelay           *string                `hcl:"max_delay,optional"`
+	RequestTimeout     *string                `hcl:"request_timeout,optional"`
+	BackoffStrategy    *string                `hcl:"backoff_strategy,optional"`
+	RecordDir          *string                `hcl:"record_dir,optional"`
+	ReplayDir          *string                `hcl:"replay_dir,optional"`
+	SnapshotPath       *string                `hcl:"snapshot_path,optional"`
+	StrictDecoding     *string                `hcl:"strict_decoding,optional"`
+	ProxyURL           *string                `hcl:"proxy_url,optional"`
+	CACertFile         *string                `hcl:"ca_cert_file,optional"`
+	InsecureSkipVerify *bool                  `hcl:"insecure_skip_verify,optional"`
+	ClientCertFile     *string                `hcl:"client_cert_file,optional"`
+	ClientKeyFile      *string                `hcl:"client_key_file,optional"`
+	TLSMinVersion      *string                `hcl:"tls_min_version,optional"`
+	Vendors            []string               `hcl:"vendors,optional"`
+	VendorsFile        *string                `hcl:"vendors_file,optional"`
+	DeviceMatchRules   []string               `hcl:"device_match_rules,optional"`
+	Since              *string                `hcl:"since,optional"`
+	RiskModel          *RiskModelConfig       `hcl:"risk_model,block"`
+	Recency            *RecencyConfig         `hcl:"recency,block"`
+	Watchlist          *WatchlistConfig       `hcl:"watchlist,block"`
+	PasswordPolicies   []PasswordPolicyConfig `hcl:"password_policy,block"`
+
+	// Body is the parsed connection block, which validateConfig uses to
+	// report errors at the position of the setting
+	Body hcl.Body `hcl:",body"`
+}
+
+// RiskModelConfig holds the weights and parameters used to compute the domain
+// exposure risk score. Unset values fall back to the defaults in
+// defaultRiskModel.
+type RiskModelConfig struct {
+	InfectionWeight     *float64 `hcl:"infection_weight,optional"`
+	RecencyWeight       *float64 `hcl:"recency_weight,optional"`
+	PasswordWeight      *float64 `hcl:"password_weight,optional"`
+	StealerFamilyWeight *float64 `hcl:"stealer_family_weight,optional"`
+	InfectionSaturation *int     `hcl:"infection_saturation,optional"`
+	RecencyHalfLifeDays *int     `hcl:"recency_half_life_days,optional"`
+	HighRiskFamilies    []string `hcl:"high_risk_families,optional"`
+}
+
+// RecencyConfig holds the upper bounds, in days since the compromise, of the
+// recency classes of infections. Unset values fall back to the defaults in
+// defaultRecencyPolicy.
+type RecencyConfig struct {
+	CriticalDays *int `hcl:"critical_days,optional"`
+	RecentDays   *int `hcl:"recent_days,optional"`
+	AgingDays    *int `hcl:"aging_days,optional"`
+}
+
+// WatchlistConfig lists the assets monitored by hudsonrock_watchlist_exposure
+// and bounds the concurrent lookups of the fan-out tables.
+type WatchlistConfig struct {
+	Emails         []string `hcl:"emails,optional"`
+	Domains        []string `hcl:"domains,optional"`
+	IPs            []string `hcl:"ips,optional"`
+	Usernames      []string `hcl:"usernames,optional"`
+	MaxConcurrency *int     `hcl:"max_concurrency,optional"`
+}
+
+// PasswordPolicyConfig is a rule evaluated by hudsonrock_password_policy_check
+// against a domain's password statistics.
+type PasswordPolicyConfig struct {
+	Name       string   `hcl:"name,label"`
+	Population *string  `hcl:"population,optional"`
+	Strengths  []string `hcl:"strengths"`
+	MaxPercent *float64 `hcl:"max_percent,optional"`
+	MinPercent *float64 `hcl:"min_percent,optional"`
 }
 
 func ConfigInstance() interface{} {
@@ -22,3 +98,18 @@ func GetConfig(connection *plugin.Connection) HudsonRockConfig {
 
 	return config
 }
+
+// parseDuration parses a timing setting, either a Go duration string such as
+// "250ms" or "2s", or a plain number of seconds. HCL turns numbers into
+// strings for the string fields of the config.
+func parseDuration(value string) (time.Duration, error) {
+	value = strings.TrimSpace(value)
+	if seconds, err := strconv.ParseFloat(value, 64); err == nil && math.Abs(seconds) < math.MaxInt64/float64(time.Second) {
+		return time.Duration(seconds * float64(time.Second)), nil
+	}
+	d, err := time.ParseDuration(value)
+	if err != nil {
+		return 0, fmt.Errorf("invalid duration %q, must be a number of seconds or a duration such as 250ms or 2s", value)
+	}
+	return d, nil
+}
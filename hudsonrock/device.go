@@ -0,0 +1,245 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+// Infection fields that device matching rules may combine.
+const (
+	deviceFieldComputerName    = "computer_name"
+	deviceFieldIP              = "ip"
+	deviceFieldDateCompromised = "date_compromised"
+	deviceFieldOperatingSystem = "operating_system"
+	deviceFieldMalwarePath     = "malware_path"
+)
+
+// defaultDeviceMatchRules treat two infections as the same machine when they
+// share a computer name and either an IP address or a compromise timestamp.
+var defaultDeviceMatchRules = []string{
+	deviceFieldComputerName + "+" + deviceFieldIP,
+	deviceFieldComputerName + "+" + deviceFieldDateCompromised,
+}
+
+// deviceMatchRule is a set of infection fields that must all be present and
+// equal for two infections to belong to the same device.
+type deviceMatchRule []string
+
+// parseDeviceMatchRules parses rules of the form "computer_name+ip". Unset
+// rules fall back to defaultDeviceMatchRules.
+func parseDeviceMatchRules(rules []string) ([]deviceMatchRule, error) {
+	if len(rules) == 0 {
+		rules = defaultDeviceMatchRules
+	}
+
+	var parsed []deviceMatchRule
+	for _, rule := range rules {
+		var fields deviceMatchRule
+		for _, field := range strings.Split(rule, "+") {
+			field = strings.TrimSpace(field)
+			switch field {
+			case deviceFieldComputerName, deviceFieldIP, deviceFieldDateCompromised, deviceFieldOperatingSystem, deviceFieldMalwarePath:
+				fields = append(fields, field)
+			default:
+				return nil, fmt.Errorf("device_match_rules: invalid field %q in rule %q, must be one of %s, %s, %s, %s or %s", field, rule,
+					deviceFieldComputerName, deviceFieldIP, deviceFieldDateCompromised, deviceFieldOperatingSystem, deviceFieldMalwarePath)
+			}
+		}
+		parsed = append(parsed, fields)
+	}
+	return parsed, nil
+}
+
+// key returns the normalized values of the rule's fields for an infection,
+// or false if any of them is empty.
+func (r deviceMatchRule) key(infection Infection) (string, bool) {
+	values := make([]string, len(r))
+	for i, field := range r {
+		var value string
+		switch field {
+		case deviceFieldComputerName:
+			value = strings.ToLower(strings.TrimSpace(infection.ComputerName))
+		case deviceFieldIP:
+			value = strings.TrimSpace(infection.IP)
+		case deviceFieldDateCompromised:
+			if t, ok := api.ParseDate(infection.DateCompromised); ok {
+				value = t.Format("2006-01-02T15:04:05Z07:00")
+			}
+		case deviceFieldOperatingSystem:
+			value = strings.ToLower(strings.TrimSpace(infection.OperatingSystem))
+		case deviceFieldMalwarePath:
+			value = strings.ToLower(strings.ReplaceAll(strings.TrimSpace(infection.MalwarePath), "/", `\`))
+		}
+		if value == "" {
+			return "", false
+		}
+		values[i] = value
+	}
+	return strings.Join(values, "\x1f"), true
+}
+
+// groupDevices partitions infections into devices. Infections with the same
+// infection ID always belong to the same device; otherwise two infections are
+// merged when any rule matches, transitively.
+func groupDevices(infections []Infection, rules []deviceMatchRule) [][]Infection {
+	parent := make([]int, len(infections))
+	for i := range parent {
+		parent[i] = i
+	}
+	var find func(int) int
+	find = func(i int) int {
+		if parent[i] != i {
+			parent[i] = find(parent[i])
+		}
+		return parent[i]
+	}
+	union := func(i, j int) {
+		if ri, rj := find(i), find(j); ri != rj {
+			parent[rj] = ri
+		}
+	}
+
+	first := map[string]int{}
+	merge := func(key string, i int) {
+		if j, ok := first[key]; ok {
+			union(j, i)
+		} else {
+			first[key] = i
+		}
+	}
+	for i, infection := range infections {
+		merge("id\x1e"+infection.InfectionID, i)
+		for n, rule := range rules {
+			if key, ok := rule.key(infection); ok {
+				merge(fmt.Sprintf("%d\x1e%s", n, key), i)
+			}
+		}
+	}
+
+	groups := map[int][]Infection{}
+	var roots []int
+	for i, infection := range infections {
+		root := find(i)
+		if _, ok := groups[root]; !ok {
+			roots = append(roots, root)
+		}
+		groups[root] = append(groups[root], infection)
+	}
+	devices := make([][]Infection, len(roots))
+	for i, root := range roots {
+		devices[i] = groups[root]
+	}
+	return devices
+}
+
+// stringsFromQual parses a JSON array of strings given in a key column,
+// dropping blank values and case-insensitive duplicates.
+func stringsFromQual(d *plugin.QueryData, column string) ([]string, error) {
+	raw := d.EqualsQuals[column].GetJsonbValue()
+	var values []string
+	if err := json.Unmarshal([]byte(raw), &values); err != nil {
+		return nil, fmt.Errorf("%s must be a JSON array of strings: %w", column, err)
+	}
+
+	var result []string
+	seen := map[string]bool{}
+	for _, value := range values {
+		value = strings.TrimSpace(value)
+		if value == "" || seen[strings.ToLower(value)] {
+			continue
+		}
+		seen[strings.ToLower(value)] = true
+		result = append(result, value)
+	}
+	return result, nil
+}
+
+// identifiersFromQual parses a JSON array of identifiers given in a key
+// column and infers the lookup type of each one.
+func identifiersFromQual(d *plugin.QueryData, column string) ([]identifier, error) {
+	values, err := stringsFromQual(d, column)
+	if err != nil {
+		return nil, err
+	}
+	identifiers := make([]identifier, len(values))
+	for i, value := range values {
+		identifiers[i] = identifier{identifierLookupType(value), value}
+	}
+	return identifiers, nil
+}
+
+// qualOrValues returns the JSON qual given for column unchanged, so Postgres
+// keeps the rows when it rechecks the condition, or the values used in its
+// place when the qual is not set.
+func qualOrValues(d *plugin.QueryData, column string, identifiers []identifier) json.RawMessage {
+	if d.EqualsQuals[column] != nil {
+		return json.RawMessage(d.EqualsQuals[column].GetJsonbValue())
+	}
+	values := make([]string, len(identifiers))
+	for i, id := range identifiers {
+		values[i] = id.Value
+	}
+	raw, _ := json.Marshal(values)
+	return raw
+}
+
+// lookupIdentifiers looks up the infections of every identifier kept by
+// recency, running up to limit lookups at once. It fails if any lookup fails.
+func lookupIdentifiers(ctx context.Context, client *api.Client, recency recencyPolicy, identifiers []identifier, limit int) ([]Infection, error) {
+	results := make([][]Infection, len(identifiers))
+	var (
+		mu       sync.Mutex
+		firstErr error
+	)
+	forEachConcurrently(ctx, identifiers, limit, func(ctx context.Context, i int, id identifier) {
+		infections, err := lookupInfections(ctx, client, recency, id.Type, id.Value)
+		if err != nil {
+			mu.Lock()
+			if firstErr == nil {
+				firstErr = fmt.Errorf("%s lookup for %q failed: %w", id.Type, id.Value, err)
+			}
+			mu.Unlock()
+			return
+		}
+		results[i] = infections
+	})
+	if firstErr != nil {
+		return nil, firstErr
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var infections []Infection
+	for _, result := range results {
+		infections = append(infections, result...)
+	}
+	return infections, nil
+}
+
+// infectionRaws returns the stealer objects of the infections in ids, in the
+// same order.
+func infectionRaws(ids []string, raws map[string]json.RawMessage) []json.RawMessage {
+	result := make([]json.RawMessage, 0, len(ids))
+	for _, id := range ids {
+		result = append(result, raws[id])
+	}
+	return result
+}
+
+// sortedKeys returns the keys of a set in ascending order.
+func sortedKeys(set map[string]struct{}) []string {
+	keys := make([]string, 0, len(set))
+	for key := range set {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
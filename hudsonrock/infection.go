@@ -0,0 +1,140 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+)
+
+// Lookup types used by tables that combine email, IP and username lookups.
+const (
+	lookupTypeEmail    = "email"
+	lookupTypeIP       = "ip"
+	lookupTypeUsername = "username"
+)
+
+// Infection is a single infection returned by an email, IP or username
+// lookup, together with the lookup that found it.
+type Infection struct {
+	LookupType             string
+	LookupValue            string
+	InfectionID            string
+	DateCompromised        string
+	ComputerName           string
+	OperatingSystem        string
+	MalwarePath            string
+	IP                     string
+	StealerFamily          string
+	Antiviruses            []string
+	TopPasswords           []string
+	TopLogins              []string
+	TotalCorporateServices int
+	TotalUserServices      int
+	// Raw is the stealer object as returned by the API
+	Raw json.RawMessage
+}
+
+// lookupInfections runs the lookup matching lookupType and returns its
+// infections, leaving out those compromised before the since cutoff of
+// recency.
+func lookupInfections(ctx context.Context, client *api.Client, recency recencyPolicy, lookupType, value string) ([]Infection, error) {
+	var infections []Infection
+
+	switch lookupType {
+	case lookupTypeEmail:
+		output, err := client.SearchByEmail(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range output.Stealers {
+			infections = append(infections, Infection{
+				InfectionID:            s.InfectionID(),
+				DateCompromised:        s.DateCompromised,
+				ComputerName:           s.ComputerName,
+				OperatingSystem:        s.OperatingSystem,
+				MalwarePath:            s.MalwarePath,
+				IP:                     s.IP,
+				Antiviruses:            s.Antiviruses,
+				TopPasswords:           s.TopPasswords,
+				TopLogins:              s.TopLogins,
+				TotalCorporateServices: int(s.TotalCorporateServices),
+				TotalUserServices:      int(s.TotalUserServices),
+				Raw:                    s.Raw,
+			})
+		}
+	case lookupTypeIP:
+		output, err := client.SearchByIp(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range output.Stealers {
+			infections = append(infections, Infection{
+				InfectionID:            s.InfectionID(),
+				DateCompromised:        s.DateCompromised,
+				ComputerName:           s.ComputerName,
+				OperatingSystem:        s.OperatingSystem,
+				MalwarePath:            s.MalwarePath,
+				IP:                     s.IP,
+				Antiviruses:            s.Antiviruses,
+				TopPasswords:           s.TopPasswords,
+				TopLogins:              s.TopLogins,
+				TotalCorporateServices: int(s.TotalCorporateServices),
+				TotalUserServices:      int(s.TotalUserServices),
+				Raw:                    s.Raw,
+			})
+		}
+	case lookupTypeUsername:
+		output, err := client.SearchByUsername(ctx, value)
+		if err != nil {
+			return nil, err
+		}
+		for _, s := range output.Stealers {
+			infections = append(infections, Infection{
+				InfectionID:            s.InfectionID(),
+				DateCompromised:        s.DateCompromised,
+				ComputerName:           s.ComputerName,
+				OperatingSystem:        s.OperatingSystem,
+				MalwarePath:            s.MalwarePath,
+				IP:                     s.IP,
+				StealerFamily:          s.StealerFamily,
+				Antiviruses:            s.Antiviruses,
+				TopPasswords:           s.TopPasswords,
+				TopLogins:              s.TopLogins,
+				TotalCorporateServices: int(s.TotalCorporateServices),
+				TotalUserServices:      int(s.TotalUserServices),
+				Raw:                    s.Raw,
+			})
+		}
+	default:
+		return nil, fmt.Errorf("unsupported lookup type %q", lookupType)
+	}
+
+	kept := infections[:0]
+	for _, infection := range infections {
+		if !recency.include(infection.DateCompromised) {
+			continue
+		}
+		infection.LookupType = lookupType
+		infection.LookupValue = value
+		kept = append(kept, infection)
+	}
+	return kept, nil
+}
+
+// identifierLookupType guesses the lookup to use for an identifier: IP
+// addresses are looked up by IP, values containing '@' by email and anything
+// else by username.
+func identifierLookupType(identifier string) string {
+	switch {
+	case net.ParseIP(identifier) != nil:
+		return lookupTypeIP
+	case strings.Contains(identifier, "@"):
+		return lookupTypeEmail
+	default:
+		return lookupTypeUsername
+	}
+}
@@ -0,0 +1,183 @@
+package hudsonrock
+
+import (
+	"fmt"
+	"math"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+)
+
+// Populations whose password statistics a policy can be evaluated against.
+const (
+	passwordPopulationEmployees = "employees"
+	passwordPopulationUsers     = "users"
+)
+
+// Password strength bins reported by the domain search.
+const (
+	passwordStrengthTooWeak = "too_weak"
+	passwordStrengthWeak    = "weak"
+	passwordStrengthMedium  = "medium"
+	passwordStrengthStrong  = "strong"
+)
+
+// passwordPolicy is a resolved password_policy rule: the share of passwords
+// in the given strength bins must stay within the thresholds.
+type passwordPolicy struct {
+	Name       string
+	Population string
+	Strengths  []string
+	MaxPercent *float64
+	MinPercent *float64
+}
+
+// defaultPasswordPolicies are used when the connection defines no
+// password_policy blocks.
+var defaultPasswordPolicies = []passwordPolicy{
+	{
+		Name:       "employee_weak_passwords",
+		Population: passwordPopulationEmployees,
+		Strengths:  []string{passwordStrengthTooWeak, passwordStrengthWeak},
+		MaxPercent: float64Ptr(20),
+	},
+	{
+		Name:       "user_weak_passwords",
+		Population: passwordPopulationUsers,
+		Strengths:  []string{passwordStrengthTooWeak, passwordStrengthWeak},
+		MaxPercent: float64Ptr(40),
+	},
+}
+
+func float64Ptr(v float64) *float64 {
+	return &v
+}
+
+// newPasswordPolicies validates the connection's password_policy blocks,
+// falling back to defaultPasswordPolicies if there are none.
+func newPasswordPolicies(configs []PasswordPolicyConfig) ([]passwordPolicy, error) {
+	if len(configs) == 0 {
+		return defaultPasswordPolicies, nil
+	}
+
+	names := map[string]bool{}
+	policies := make([]passwordPolicy, 0, len(configs))
+	for _, config := range configs {
+		if names[config.Name] {
+			return nil, fmt.Errorf("password_policy %q is defined more than once", config.Name)
+		}
+		names[config.Name] = true
+
+		policy := passwordPolicy{
+			Name:       config.Name,
+			Population: passwordPopulationEmployees,
+			MaxPercent: config.MaxPercent,
+			MinPercent: config.MinPercent,
+		}
+		if config.Population != nil {
+			policy.Population = *config.Population
+		}
+		if policy.Population != passwordPopulationEmployees && policy.Population != passwordPopulationUsers {
+			return nil, fmt.Errorf("password_policy %q: population must be %s or %s, got %q", config.Name, passwordPopulationEmployees, passwordPopulationUsers, policy.Population)
+		}
+
+		if len(config.Strengths) == 0 {
+			return nil, fmt.Errorf("password_policy %q: strengths must not be empty", config.Name)
+		}
+		seen := map[string]bool{}
+		for _, strength := range config.Strengths {
+			switch strength {
+			case passwordStrengthTooWeak, passwordStrengthWeak, passwordStrengthMedium, passwordStrengthStrong:
+			default:
+				return nil, fmt.Errorf("password_policy %q: invalid strength %q, must be one of %s, %s, %s or %s", config.Name, strength,
+					passwordStrengthTooWeak, passwordStrengthWeak, passwordStrengthMedium, passwordStrengthStrong)
+			}
+			if !seen[strength] {
+				seen[strength] = true
+				policy.Strengths = append(policy.Strengths, strength)
+			}
+		}
+
+		if policy.MaxPercent == nil && policy.MinPercent == nil {
+			return nil, fmt.Errorf("password_policy %q: at least one of max_percent or min_percent must be set", config.Name)
+		}
+		for _, threshold := range []*float64{policy.MaxPercent, policy.MinPercent} {
+			if threshold != nil && (*threshold < 0 || *threshold > 100) {
+				return nil, fmt.Errorf("password_policy %q: thresholds must be between 0 and 100", config.Name)
+			}
+		}
+		if policy.MaxPercent != nil && policy.MinPercent != nil && *policy.MinPercent > *policy.MaxPercent {
+			return nil, fmt.Errorf("password_policy %q: min_percent must not be greater than max_percent", config.Name)
+		}
+		policies = append(policies, policy)
+	}
+	return policies, nil
+}
+
+// evaluate checks the policy against a domain search result. Passed is nil
+// when the domain has no password statistics for the policy's population.
+func (p passwordPolicy) evaluate(domain string, result api.DomainSearchResponse) *PasswordPolicyCheck {
+	check := &PasswordPolicyCheck{
+		Domain:     domain,
+		Policy:     p.Name,
+		Population: p.Population,
+		Strengths:  p.Strengths,
+		MaxPercent: p.MaxPercent,
+		MinPercent: p.MinPercent,
+		Status:     "no_data",
+		Raw:        result.Raw,
+	}
+
+	stats := result.EmployeePasswords
+	if p.Population == passwordPopulationUsers {
+		stats = result.UserPasswords
+	}
+	check.TotalPasswords = int(stats.TotalPass)
+	if !stats.HasStats {
+		check.Reason = fmt.Sprintf("no password statistics are available for %s", p.Population)
+		return check
+	}
+
+	var actual float64
+	for _, strength := range p.Strengths {
+		switch strength {
+		case passwordStrengthTooWeak:
+			actual += float64(stats.TooWeak.Perc)
+		case passwordStrengthWeak:
+			actual += float64(stats.Weak.Perc)
+		case passwordStrengthMedium:
+			actual += float64(stats.Medium.Perc)
+		case passwordStrengthStrong:
+			actual += float64(stats.Strong.Perc)
+		}
+	}
+	actual = round2(math.Min(100, actual))
+	check.ActualPercent = &actual
+
+	passed := (p.MaxPercent == nil || actual <= *p.MaxPercent) && (p.MinPercent == nil || actual >= *p.MinPercent)
+	check.Passed = &passed
+	check.Status = "fail"
+	if passed {
+		check.Status = "pass"
+	}
+	check.Reason = p.reason(actual, passed)
+	return check
+}
+
+// reason describes the outcome of the policy in a sentence suitable for a
+// compliance report.
+func (p passwordPolicy) reason(actual float64, passed bool) string {
+	var bounds []string
+	if p.MinPercent != nil {
+		bounds = append(bounds, fmt.Sprintf("at least %g%%", *p.MinPercent))
+	}
+	if p.MaxPercent != nil {
+		bounds = append(bounds, fmt.Sprintf("at most %g%%", *p.MaxPercent))
+	}
+	verb := "is"
+	if !passed {
+		verb = "is not"
+	}
+	return fmt.Sprintf("%g%% of %s passwords are %s, which %s %s", actual, strings.TrimSuffix(p.Population, "s"),
+		strings.Join(p.Strengths, " or "), verb, strings.Join(bounds, " and "))
+}
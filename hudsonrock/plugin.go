@@ -5,24 +5,49 @@ import (
 
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
 	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+	"github.com/turbot/steampipe-plugin-sdk/v5/rate_limiter"
 )
 
 const pluginName = "steampipe-plugin-hudsonrock"
 
 // Plugin returns the Hudson Rock plugin definition.
 func Plugin(ctx context.Context) *plugin.Plugin {
+	tables := map[string]*plugin.Table{
+		"hudsonrock_device":                tableHudsonrockDevice(ctx),
+		"hudsonrock_domain_history":        tableHudsonrockDomainHistory(ctx),
+		"hudsonrock_domain_risk":           tableHudsonrockDomainRisk(ctx),
+		"hudsonrock_infection_timeline":    tableHudsonrockInfectionTimeline(ctx),
+		"hudsonrock_new_infection":         tableHudsonrockNewInfection(ctx),
+		"hudsonrock_password_policy_check": tableHudsonrockPasswordPolicyCheck(ctx),
+		"hudsonrock_pivot":                 tableHudsonrockPivot(ctx),
+		"hudsonrock_search_by_domain":      tableHudsonrockSearchByDomain(ctx),
+		"hudsonrock_search_by_email":       tableHudsonrockSearchByEmail(ctx),
+		"hudsonrock_search_by_ip":          tableHudsonrockSearchByIp(ctx),
+		"hudsonrock_search_by_username":    tableHudsonrockSearchByUsername(ctx),
+		"hudsonrock_url_by_domain":         tableHudsonrockUrlByDomain(ctx),
+		"hudsonrock_vendor_exposure":       tableHudsonrockVendorExposure(ctx),
+		"hudsonrock_watchlist_exposure":    tableHudsonrockWatchlistExposure(ctx),
+	}
+
 	return &plugin.Plugin{
 		Name:             pluginName,
-		DefaultTransform: transform.FromGo().NullIfEmptySlice(),
+		DefaultTransform: transform.FromGo().Transform(nullIfEmptySlice),
 		ConnectionConfigSchema: &plugin.ConnectionConfigSchema{
 			NewInstance: ConfigInstance,
 		},
-		TableMap: map[string]*plugin.Table{
-			"hudsonrock_search_by_domain":   tableHudsonrockSearchByDomain(ctx),
-			"hudsonrock_search_by_email":    tableHudsonrockSearchByEmail(ctx),
-			"hudsonrock_search_by_ip":       tableHudsonrockSearchByIp(ctx),
-			"hudsonrock_search_by_username": tableHudsonrockSearchByUsername(ctx),
-			"hudsonrock_url_by_domain":      tableHudsonrockUrlByDomain(ctx),
+		RateLimiters: []*rate_limiter.Definition{
+			{
+				Name:       "hudsonrock_fan_out",
+				FillRate:   5,
+				BucketSize: 5,
+				Scope:      []string{"connection"},
+				Where:      "fan_out = 'true'",
+			},
 		},
+		TableMap: tables,
+		// The schema is static. TableMapFunc is deliberately used as a hook to
+		// validate the connection config when it loads, and always returns
+		// TableMap.
+		TableMapFunc: tableMapFunc(tables),
 	}
 }
@@ -0,0 +1,121 @@
+package hudsonrock
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+)
+
+// Recency classes of an infection, from most to least urgent.
+const (
+	recencyClassCritical = "critical"
+	recencyClassRecent   = "recent"
+	recencyClassAging    = "aging"
+	recencyClassStale    = "stale"
+)
+
+// recencyPolicy holds the resolved class boundaries, in days since the
+// compromise, and the optional cutoff before which infections are dropped.
+type recencyPolicy struct {
+	CriticalDays int
+	RecentDays   int
+	AgingDays    int
+	Since        *time.Time
+	now          time.Time
+}
+
+var defaultRecencyPolicy = recencyPolicy{
+	CriticalDays: 7,
+	RecentDays:   90,
+	AgingDays:    365,
+}
+
+// newRecencyPolicy applies the connection's recency block and since setting
+// on top of the default policy. Relative since values are resolved against
+// now.
+func newRecencyPolicy(config HudsonRockConfig, now time.Time) (recencyPolicy, error) {
+	policy := defaultRecencyPolicy
+	policy.now = now
+	if config.Recency != nil {
+		if config.Recency.CriticalDays != nil {
+			policy.CriticalDays = *config.Recency.CriticalDays
+		}
+		if config.Recency.RecentDays != nil {
+			policy.RecentDays = *config.Recency.RecentDays
+		}
+		if config.Recency.AgingDays != nil {
+			policy.AgingDays = *config.Recency.AgingDays
+		}
+	}
+	if policy.CriticalDays < 0 {
+		return policy, errors.New("recency critical_days must be greater than or equal to 0")
+	}
+	if policy.RecentDays < policy.CriticalDays || policy.AgingDays < policy.RecentDays {
+		return policy, errors.New("recency boundaries must satisfy critical_days <= recent_days <= aging_days")
+	}
+
+	if config.Since != nil && strings.TrimSpace(*config.Since) != "" {
+		since, err := parseSince(*config.Since, now)
+		if err != nil {
+			return policy, err
+		}
+		policy.Since = &since
+	}
+	return policy, nil
+}
+
+// parseSince accepts an absolute date such as "2024-01-01", or an age such as
+// "365d" or "720h" that is subtracted from now.
+func parseSince(value string, now time.Time) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if t, ok := api.ParseDate(value); ok {
+		return t, nil
+	}
+	if days, ok := strings.CutSuffix(value, "d"); ok {
+		if n, err := strconv.Atoi(days); err == nil && n >= 0 {
+			return now.AddDate(0, 0, -n), nil
+		}
+	}
+	if age, err := time.ParseDuration(value); err == nil && age >= 0 {
+		return now.Add(-age), nil
+	}
+	return time.Time{}, fmt.Errorf("since must be a date such as 2024-01-01 or an age such as 365d, got %q", value)
+}
+
+// include reports whether an infection compromised at dateCompromised is
+// kept by the since cutoff. Infections without a parsable date are kept.
+func (p recencyPolicy) include(dateCompromised string) bool {
+	if p.Since == nil {
+		return true
+	}
+	t, ok := api.ParseDate(dateCompromised)
+	return !ok || !t.Before(*p.Since)
+}
+
+// classify returns the number of whole days since the compromise and its
+// recency class, or nil and "" if the date cannot be parsed.
+func (p recencyPolicy) classify(dateCompromised string) (*int, string) {
+	t, ok := api.ParseDate(dateCompromised)
+	if !ok {
+		return nil, ""
+	}
+	days := int(p.now.Sub(t).Hours() / 24)
+	if days < 0 {
+		days = 0
+	}
+
+	switch {
+	case days <= p.CriticalDays:
+		return &days, recencyClassCritical
+	case days <= p.RecentDays:
+		return &days, recencyClassRecent
+	case days <= p.AgingDays:
+		return &days, recencyClassAging
+	default:
+		return &days, recencyClassStale
+	}
+}
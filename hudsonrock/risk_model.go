@@ -0,0 +1,190 @@
+package hudsonrock
+
+import (
+	"encoding/json"
+	"errors"
+	"math"
+	"strings"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+)
+
+// riskModel holds the resolved weights and parameters of the domain exposure
+// risk score.
+type riskModel struct {
+	InfectionWeight     float64  `json:"infection_weight"`
+	RecencyWeight       float64  `json:"recency_weight"`
+	PasswordWeight      float64  `json:"password_weight"`
+	StealerFamilyWeight float64  `json:"stealer_family_weight"`
+	InfectionSaturation int      `json:"infection_saturation"`
+	RecencyHalfLifeDays int      `json:"recency_half_life_days"`
+	HighRiskFamilies    []string `json:"high_risk_families"`
+}
+
+var defaultRiskModel = riskModel{
+	InfectionWeight:     40,
+	RecencyWeight:       25,
+	PasswordWeight:      20,
+	StealerFamilyWeight: 15,
+	InfectionSaturation: 1000,
+	RecencyHalfLifeDays: 180,
+	HighRiskFamilies:    []string{"Lumma", "RedLine", "Raccoon", "Vidar", "StealC", "RisePro", "Atomic"},
+}
+
+// newRiskModel applies the connection's risk_model block on top of the
+// default model.
+func newRiskModel(config *RiskModelConfig) (riskModel, error) {
+	model := defaultRiskModel
+	if config != nil {
+		if config.InfectionWeight != nil {
+			model.InfectionWeight = *config.InfectionWeight
+		}
+		if config.RecencyWeight != nil {
+			model.RecencyWeight = *config.RecencyWeight
+		}
+		if config.PasswordWeight != nil {
+			model.PasswordWeight = *config.PasswordWeight
+		}
+		if config.StealerFamilyWeight != nil {
+			model.StealerFamilyWeight = *config.StealerFamilyWeight
+		}
+		if config.InfectionSaturation != nil {
+			model.InfectionSaturation = *config.InfectionSaturation
+		}
+		if config.RecencyHalfLifeDays != nil {
+			model.RecencyHalfLifeDays = *config.RecencyHalfLifeDays
+		}
+		if config.HighRiskFamilies != nil {
+			model.HighRiskFamilies = config.HighRiskFamilies
+		}
+	}
+
+	if model.InfectionWeight < 0 || model.RecencyWeight < 0 || model.PasswordWeight < 0 || model.StealerFamilyWeight < 0 {
+		return model, errors.New("risk_model weights must be greater than or equal to 0")
+	}
+	if model.totalWeight() == 0 {
+		return model, errors.New("risk_model weights must not all be 0")
+	}
+	if model.InfectionSaturation < 1 {
+		return model, errors.New("risk_model infection_saturation must be greater than or equal to 1")
+	}
+	if model.RecencyHalfLifeDays < 1 {
+		return model, errors.New("risk_model recency_half_life_days must be greater than or equal to 1")
+	}
+	return model, nil
+}
+
+func (m riskModel) totalWeight() float64 {
+	return m.InfectionWeight + m.RecencyWeight + m.PasswordWeight + m.StealerFamilyWeight
+}
+
+// DomainRisk is the explained risk score of a domain. The factor
+// contributions add up to the score.
+type DomainRisk struct {
+	Domain                    string
+	RiskScore                 float64
+	RiskLevel                 string
+	InfectionContribution     float64
+	RecencyContribution       float64
+	PasswordContribution      float64
+	StealerFamilyContribution float64
+	Infections                int
+	Employees                 int
+	Users                     int
+	LastCompromised           *time.Time
+	DaysSinceLastCompromise   *int
+	WeakPasswordPercent       *float64
+	HighRiskFamilyPercent     *float64
+	RiskModel                 riskModel
+	Raw                       json.RawMessage
+}
+
+// score computes the risk of a domain search result. Each factor is scaled
+// to [0, 1] and weighted:
+//
+//   - infections: employees and users on a logarithmic scale, saturating at
+//     infection_saturation.
+//   - recency: exponential decay of the most recent compromise with a half
+//     life of recency_half_life_days.
+//   - passwords: share of too weak and weak passwords, using employee stats
+//     when available and user stats otherwise.
+//   - stealer families: share of stealers from high_risk_families.
+func (m riskModel) score(domain string, result api.DomainSearchResponse, now time.Time) DomainRisk {
+	risk := DomainRisk{
+		Domain:     domain,
+		Employees:  int(result.Employees),
+		Users:      int(result.Users),
+		Infections: int(result.Employees + result.Users),
+		RiskModel:  m,
+		Raw:        result.Raw,
+	}
+
+	infectionFactor := math.Min(1, math.Log1p(float64(risk.Infections))/math.Log1p(float64(m.InfectionSaturation)))
+
+	var recencyFactor float64
+	for _, value := range []string{result.LastEmployeeCompromised, result.LastUserCompromised} {
+		if t, ok := api.ParseDate(value); ok && (risk.LastCompromised == nil || t.After(*risk.LastCompromised)) {
+			risk.LastCompromised = &t
+		}
+	}
+	if risk.LastCompromised != nil {
+		days := int(math.Max(0, now.Sub(*risk.LastCompromised).Hours()/24))
+		risk.DaysSinceLastCompromise = &days
+		recencyFactor = math.Pow(0.5, float64(days)/float64(m.RecencyHalfLifeDays))
+	}
+
+	var passwordFactor float64
+	for _, stats := range []api.PasswordStats{result.EmployeePasswords, result.UserPasswords} {
+		if stats.HasStats {
+			weak := math.Min(100, float64(stats.TooWeak.Perc+stats.Weak.Perc))
+			risk.WeakPasswordPercent = &weak
+			passwordFactor = weak / 100
+			break
+		}
+	}
+
+	var familyFactor float64
+	var total, highRisk int
+	for family, count := range result.StealerFamilies {
+		total += int(count)
+		for _, name := range m.HighRiskFamilies {
+			if strings.EqualFold(family, name) {
+				highRisk += int(count)
+				break
+			}
+		}
+	}
+	if total > 0 {
+		familyFactor = float64(highRisk) / float64(total)
+		percent := familyFactor * 100
+		risk.HighRiskFamilyPercent = &percent
+	}
+
+	scale := 100 / m.totalWeight()
+	risk.InfectionContribution = round2(m.InfectionWeight * infectionFactor * scale)
+	risk.RecencyContribution = round2(m.RecencyWeight * recencyFactor * scale)
+	risk.PasswordContribution = round2(m.PasswordWeight * passwordFactor * scale)
+	risk.StealerFamilyContribution = round2(m.StealerFamilyWeight * familyFactor * scale)
+	risk.RiskScore = round2(risk.InfectionContribution + risk.RecencyContribution + risk.PasswordContribution + risk.StealerFamilyContribution)
+	risk.RiskLevel = riskLevel(risk.RiskScore)
+
+	return risk
+}
+
+func riskLevel(score float64) string {
+	switch {
+	case score >= 75:
+		return "critical"
+	case score >= 50:
+		return "high"
+	case score >= 25:
+		return "medium"
+	default:
+		return "low"
+	}
+}
+
+func round2(value float64) float64 {
+	return math.Round(value*100) / 100
+}
@@ -0,0 +1,94 @@
+package hudsonrock
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+	"github.com/turbot/steampipe-plugin-hudsonrock/store"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+var (
+	storesMu sync.Mutex
+	stores   = map[string]*store.Store{}
+)
+
+// getStore returns the local snapshot store configured for the connection,
+// or nil if snapshot_path is not set. Stores are opened once per path and
+// shared across queries.
+func getStore(ctx context.Context, d *plugin.QueryData) (*store.Store, error) {
+	config := GetConfig(d.Connection)
+	if config.SnapshotPath == nil || *config.SnapshotPath == "" {
+		return nil, nil
+	}
+	path, err := expandPath(*config.SnapshotPath)
+	if err != nil {
+		return nil, err
+	}
+
+	storesMu.Lock()
+	defer storesMu.Unlock()
+
+	if s, ok := stores[path]; ok {
+		return s, nil
+	}
+	s, err := store.Open(ctx, path)
+	if err != nil {
+		return nil, err
+	}
+	stores[path] = s
+	return s, nil
+}
+
+// expandPath resolves a leading ~ to the user's home directory.
+func expandPath(path string) (string, error) {
+	if path != "~" && !strings.HasPrefix(path, "~/") {
+		return filepath.Clean(path), nil
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(home, strings.TrimPrefix(path, "~")), nil
+}
+
+// recordDomainSnapshot records a hudsonrock_search_by_domain result when a
+// snapshot store is configured, so it shows up in hudsonrock_domain_history.
+// Other tables searching domains do not record snapshots, so that a fan-out
+// query does not add several points for the same moment. Failing to record a
+// snapshot is logged but does not fail the search.
+func recordDomainSnapshot(ctx context.Context, d *plugin.QueryData, domain string, result api.DomainSearchResponse) {
+	s, err := getStore(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Warn("recordDomainSnapshot", "store_error", err)
+		return
+	}
+	if s == nil {
+		return
+	}
+
+	families := make(map[string]int64, len(result.StealerFamilies))
+	for family, count := range result.StealerFamilies {
+		families[family] = int64(count)
+	}
+	snapshot := store.DomainSnapshot{
+		Domain:                  strings.ToLower(domain),
+		SnapshotTime:            timeNow(),
+		Total:                   int64(result.Total),
+		TotalStealers:           int64(result.TotalStealers),
+		Employees:               int64(result.Employees),
+		Users:                   int64(result.Users),
+		ThirdParties:            int64(result.ThirdParties),
+		LastEmployeeCompromised: result.LastEmployeeCompromised,
+		LastUserCompromised:     result.LastUserCompromised,
+		StealerFamilies:         families,
+		Raw:                     result.Raw,
+	}
+	if err := s.RecordDomainSnapshot(ctx, snapshot); err != nil {
+		plugin.Logger(ctx).Warn("recordDomainSnapshot", "store_error", err)
+	}
+}
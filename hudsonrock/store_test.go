@@ -0,0 +1,95 @@
+package hudsonrock
+
+import (
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+	"github.com/turbot/steampipe-plugin-hudsonrock/store"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func TestFamiliesDelta(t *testing.T) {
+	tests := []struct {
+		name              string
+		current, previous map[string]int64
+		want              map[string]int64
+	}{
+		{name: "unchanged", current: map[string]int64{"RedLine": 3}, previous: map[string]int64{"RedLine": 3}, want: map[string]int64{}},
+		{name: "changed", current: map[string]int64{"RedLine": 5, "Lumma": 1}, previous: map[string]int64{"RedLine": 3, "Lumma": 4}, want: map[string]int64{"RedLine": 2, "Lumma": -3}},
+		{name: "new family", current: map[string]int64{"RedLine": 3, "Vidar": 2}, previous: map[string]int64{"RedLine": 3}, want: map[string]int64{"Vidar": 2}},
+		{name: "gone family", current: map[string]int64{"RedLine": 3}, previous: map[string]int64{"RedLine": 3, "Raccoon": 4}, want: map[string]int64{"Raccoon": -4}},
+		{name: "first families", current: map[string]int64{"RedLine": 1}, want: map[string]int64{"RedLine": 1}},
+		{name: "empty", want: map[string]int64{}},
+	}
+	for _, tt := range tests {
+		if got := familiesDelta(tt.current, tt.previous); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: familiesDelta = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+	if got := delta(7, 10); got == nil || *got != -3 {
+		t.Errorf("delta(7, 10) = %v, want -3", got)
+	}
+}
+
+// storeQueryData returns query data of a connection with snapshot_path set to
+// path.
+func storeQueryData(path string) *plugin.QueryData {
+	return &plugin.QueryData{Connection: &plugin.Connection{
+		Name:   testConnection,
+		Config: HudsonRockConfig{SnapshotPath: &path},
+	}}
+}
+
+func TestGetStoreSharesStores(t *testing.T) {
+	ctx := hudsonrocktest.Context()
+	dir := t.TempDir()
+	paths := []string{filepath.Join(dir, "a.db"), filepath.Join(dir, "b.db")}
+	t.Cleanup(func() {
+		storesMu.Lock()
+		defer storesMu.Unlock()
+		for _, path := range paths {
+			if s, ok := stores[path]; ok {
+				s.Close()
+				delete(stores, path)
+			}
+		}
+	})
+
+	if s, err := getStore(ctx, &plugin.QueryData{Connection: &plugin.Connection{Config: HudsonRockConfig{}}}); s != nil || err != nil {
+		t.Errorf("without snapshot_path: getStore = %v, %v, want nil", s, err)
+	}
+
+	// Concurrent queries of the same connection share one store per path
+	const queries = 16
+	got := make([]*store.Store, queries)
+	var wg sync.WaitGroup
+	for i := 0; i < queries; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			s, err := getStore(ctx, storeQueryData(paths[i%2]))
+			if err != nil {
+				t.Error(err)
+			}
+			got[i] = s
+		}(i)
+	}
+	wg.Wait()
+
+	for i, s := range got {
+		if s == nil || s != got[i%2] {
+			t.Fatalf("query %d got store %p, want %p", i, s, got[i%2])
+		}
+	}
+	if got[0] == got[1] {
+		t.Error("different paths share a store")
+	}
+	// Paths are cleaned before they are looked up
+	s, err := getStore(ctx, storeQueryData(filepath.Join(dir, ".", "a.db")))
+	if err != nil || s != got[0] {
+		t.Errorf("getStore with an unclean path = %p, %v, want %p", s, err, got[0])
+	}
+}
@@ -0,0 +1,173 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func tableHudsonrockDevice(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_device",
+		Description: "Infected machines, grouping the infections found for a list of emails, IP addresses and usernames by device.",
+		List: &plugin.ListConfig{
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "identifiers", Require: plugin.Optional},
+			},
+			Hydrate: listHudsonrockDevice,
+			Tags:    fanOutTags,
+		},
+		Columns: []*plugin.Column{
+			{Name: "identifiers", Type: proto.ColumnType_JSON, Description: "JSON array of emails, IP addresses and usernames looked up. Defaults to the emails, IP addresses and usernames on the connection's watchlist."},
+			{Name: "device_id", Type: proto.ColumnType_STRING, Description: "Identifier of the device, the smallest infection ID among its infections."},
+			{Name: "computer_names", Type: proto.ColumnType_JSON, Description: "Distinct computer names reported for the device."},
+			{Name: "ips", Type: proto.ColumnType_JSON, Description: "Distinct IP addresses reported for the device.", Transform: transform.FromField("IPs")},
+			{Name: "operating_systems", Type: proto.ColumnType_JSON, Description: "Distinct operating systems reported for the device."},
+			{Name: "identifiers_seen", Type: proto.ColumnType_JSON, Description: "Looked up identifiers whose infections belong to the device."},
+			{Name: "infection_ids", Type: proto.ColumnType_JSON, Description: "Infection IDs grouped into the device.", Transform: transform.FromField("InfectionIDs")},
+			{Name: "infection_count", Type: proto.ColumnType_INT, Description: "Number of distinct infections grouped into the device."},
+			{Name: "families", Type: proto.ColumnType_JSON, Description: "Distinct stealer malware families found on the device. Only username lookups report families."},
+			{Name: "first_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp of the earliest compromise of the device."},
+			{Name: "last_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp of the latest compromise of the device."},
+			{Name: "credentials_count", Type: proto.ColumnType_INT, Description: "Number of corporate and user service credentials stolen from the device, summed over its distinct infections."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Stealer objects of the device's infections as returned by the API, in the order of infection_ids."},
+		},
+	}
+}
+
+type Device struct {
+	Identifiers      json.RawMessage
+	DeviceID         string
+	ComputerNames    []string
+	IPs              []string
+	OperatingSystems []string
+	IdentifiersSeen  []string
+	InfectionIDs     []string
+	InfectionCount   int
+	Families         []string
+	FirstCompromised *time.Time
+	LastCompromised  *time.Time
+	CredentialsCount int
+	Raw              []json.RawMessage
+}
+
+func listHudsonrockDevice(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	config := GetConfig(d.Connection)
+	rules, err := parseDeviceMatchRules(config.DeviceMatchRules)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_device.listHudsonrockDevice", "config_error", err)
+		return nil, err
+	}
+	recency, err := newRecencyPolicy(config, timeNow())
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_device.listHudsonrockDevice", "config_error", err)
+		return nil, err
+	}
+
+	var identifiers []identifier
+	if d.EqualsQuals["identifiers"] != nil {
+		identifiers, err = identifiersFromQual(d, "identifiers")
+		if err != nil {
+			plugin.Logger(ctx).Error("hudsonrock_device.listHudsonrockDevice", "qual_error", err)
+			return nil, err
+		}
+	} else {
+		for _, asset := range watchlistAssets(config.Watchlist) {
+			if asset.Type != assetTypeDomain {
+				identifiers = append(identifiers, asset)
+			}
+		}
+	}
+	if len(identifiers) == 0 {
+		return nil, nil
+	}
+
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_device.listHudsonrockDevice", "config_error", err)
+		return nil, err
+	}
+	infections, err := lookupIdentifiers(ctx, client, recency, identifiers, watchlistMaxConcurrency(config.Watchlist))
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_device.listHudsonrockDevice", "api_error", err)
+		return nil, err
+	}
+
+	qual := qualOrValues(d, "identifiers", identifiers)
+	for _, group := range groupDevices(infections, rules) {
+		device := newDevice(group)
+		device.Identifiers = qual
+		d.StreamListItem(ctx, device)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// newDevice summarizes the infections grouped into a device. The same
+// infection found by several lookups is counted once.
+func newDevice(infections []Infection) *Device {
+	device := &Device{}
+	computerNames := map[string]struct{}{}
+	ips := map[string]struct{}{}
+	operatingSystems := map[string]struct{}{}
+	identifiersSeen := map[string]struct{}{}
+	families := map[string]struct{}{}
+	infectionIDs := map[string]struct{}{}
+	raws := map[string]json.RawMessage{}
+
+	for _, infection := range infections {
+		identifiersSeen[infection.LookupValue] = struct{}{}
+		if infection.StealerFamily != "" {
+			families[infection.StealerFamily] = struct{}{}
+		}
+		if _, ok := infectionIDs[infection.InfectionID]; ok {
+			continue
+		}
+		infectionIDs[infection.InfectionID] = struct{}{}
+		raws[infection.InfectionID] = infection.Raw
+
+		if infection.ComputerName != "" {
+			computerNames[infection.ComputerName] = struct{}{}
+		}
+		if infection.IP != "" {
+			ips[infection.IP] = struct{}{}
+		}
+		if infection.OperatingSystem != "" {
+			operatingSystems[infection.OperatingSystem] = struct{}{}
+		}
+		device.CredentialsCount += infection.TotalCorporateServices + infection.TotalUserServices
+
+		if t, ok := api.ParseDate(infection.DateCompromised); ok {
+			if device.FirstCompromised == nil || t.Before(*device.FirstCompromised) {
+				first := t
+				device.FirstCompromised = &first
+			}
+			if device.LastCompromised == nil || t.After(*device.LastCompromised) {
+				last := t
+				device.LastCompromised = &last
+			}
+		}
+	}
+
+	device.ComputerNames = sortedKeys(computerNames)
+	device.IPs = sortedKeys(ips)
+	device.OperatingSystems = sortedKeys(operatingSystems)
+	device.IdentifiersSeen = sortedKeys(identifiersSeen)
+	device.Families = sortedKeys(families)
+	device.InfectionIDs = sortedKeys(infectionIDs)
+	device.InfectionCount = len(device.InfectionIDs)
+	device.Raw = infectionRaws(device.InfectionIDs, raws)
+	if len(device.InfectionIDs) > 0 {
+		device.DeviceID = device.InfectionIDs[0]
+	}
+	return device
+}
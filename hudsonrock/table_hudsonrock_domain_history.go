@@ -0,0 +1,112 @@
+package hudsonrock
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/store"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableHudsonrockDomainHistory(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_domain_history",
+		Description: "Locally recorded snapshots of Hudson Rock domain search results, with changes since the previous snapshot.",
+		List: &plugin.ListConfig{
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "domain", Require: plugin.Optional},
+			},
+			Hydrate: listHudsonrockDomainHistory,
+		},
+		Columns: []*plugin.Column{
+			{Name: "domain", Type: proto.ColumnType_STRING, Description: "Domain of the snapshot."},
+			{Name: "snapshot_time", Type: proto.ColumnType_TIMESTAMP, Description: "Time the snapshot was recorded."},
+			{Name: "previous_snapshot_time", Type: proto.ColumnType_TIMESTAMP, Description: "Time of the previous snapshot of the domain."},
+			{Name: "total", Type: proto.ColumnType_INT, Description: "Total records found."},
+			{Name: "total_stealers", Type: proto.ColumnType_INT, Description: "Total stealers found."},
+			{Name: "employees", Type: proto.ColumnType_INT, Description: "Number of compromised employees."},
+			{Name: "users", Type: proto.ColumnType_INT, Description: "Number of compromised users."},
+			{Name: "third_parties", Type: proto.ColumnType_INT, Description: "Number of third parties."},
+			{Name: "last_employee_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp of the last employee compromise at the time of the snapshot."},
+			{Name: "last_user_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp of the last user compromise at the time of the snapshot."},
+			{Name: "stealer_families", Type: proto.ColumnType_JSON, Description: "Breakdown of stealer malware families at the time of the snapshot."},
+			{Name: "total_delta", Type: proto.ColumnType_INT, Description: "Change in total records since the previous snapshot."},
+			{Name: "total_stealers_delta", Type: proto.ColumnType_INT, Description: "Change in total stealers since the previous snapshot."},
+			{Name: "employees_delta", Type: proto.ColumnType_INT, Description: "Change in compromised employees since the previous snapshot."},
+			{Name: "users_delta", Type: proto.ColumnType_INT, Description: "Change in compromised users since the previous snapshot."},
+			{Name: "stealer_families_delta", Type: proto.ColumnType_JSON, Description: "Change in the count of each stealer family since the previous snapshot. Unchanged families are omitted."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Domain search response the snapshot was recorded from, as returned by the API without its URL lists."},
+		},
+	}
+}
+
+type DomainHistory struct {
+	store.DomainSnapshot
+	PreviousSnapshotTime *time.Time
+	TotalDelta           *int64
+	TotalStealersDelta   *int64
+	EmployeesDelta       *int64
+	UsersDelta           *int64
+	StealerFamiliesDelta map[string]int64
+}
+
+func listHudsonrockDomainHistory(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	s, err := getStore(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_domain_history.listHudsonrockDomainHistory", "store_error", err)
+		return nil, err
+	}
+	if s == nil {
+		return nil, errors.New("hudsonrock_domain_history requires snapshot_path to be set in the connection config")
+	}
+
+	domain := strings.ToLower(d.EqualsQuals["domain"].GetStringValue())
+	snapshots, err := s.DomainSnapshots(ctx, domain)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_domain_history.listHudsonrockDomainHistory", "store_error", err)
+		return nil, err
+	}
+
+	for i, snapshot := range snapshots {
+		row := &DomainHistory{DomainSnapshot: snapshot}
+		if i > 0 && snapshots[i-1].Domain == snapshot.Domain {
+			previous := snapshots[i-1]
+			row.PreviousSnapshotTime = &previous.SnapshotTime
+			row.TotalDelta = delta(snapshot.Total, previous.Total)
+			row.TotalStealersDelta = delta(snapshot.TotalStealers, previous.TotalStealers)
+			row.EmployeesDelta = delta(snapshot.Employees, previous.Employees)
+			row.UsersDelta = delta(snapshot.Users, previous.Users)
+			row.StealerFamiliesDelta = familiesDelta(snapshot.StealerFamilies, previous.StealerFamilies)
+		}
+		d.StreamListItem(ctx, row)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+func delta(current, previous int64) *int64 {
+	value := current - previous
+	return &value
+}
+
+func familiesDelta(current, previous map[string]int64) map[string]int64 {
+	deltas := map[string]int64{}
+	for family, count := range current {
+		if change := count - previous[family]; change != 0 {
+			deltas[family] = change
+		}
+	}
+	for family, count := range previous {
+		if _, ok := current[family]; !ok {
+			deltas[family] = -count
+		}
+	}
+	return deltas
+}
@@ -0,0 +1,66 @@
+package hudsonrock
+
+import (
+	"context"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableHudsonrockDomainRisk(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_domain_risk",
+		Description: "Transparent 0-100 exposure risk score for a domain, computed from Hudson Rock's domain intelligence with per-factor contributions.",
+		List: &plugin.ListConfig{
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "domain", Require: plugin.Required},
+			},
+			Hydrate: listHudsonrockDomainRisk,
+		},
+		Columns: []*plugin.Column{
+			{Name: "domain", Type: proto.ColumnType_STRING, Description: "Domain scored."},
+			{Name: "risk_score", Type: proto.ColumnType_DOUBLE, Description: "Exposure risk score between 0 and 100. Equal to the sum of the contribution columns."},
+			{Name: "risk_level", Type: proto.ColumnType_STRING, Description: "Risk level derived from the score. Possible values are: low, medium, high, critical."},
+			{Name: "infection_contribution", Type: proto.ColumnType_DOUBLE, Description: "Points contributed by the number of compromised employees and users."},
+			{Name: "recency_contribution", Type: proto.ColumnType_DOUBLE, Description: "Points contributed by how recently an employee or user was compromised."},
+			{Name: "password_contribution", Type: proto.ColumnType_DOUBLE, Description: "Points contributed by the share of too weak and weak passwords."},
+			{Name: "stealer_family_contribution", Type: proto.ColumnType_DOUBLE, Description: "Points contributed by the share of stealers from high risk families."},
+			{Name: "infections", Type: proto.ColumnType_INT, Description: "Number of compromised employees and users."},
+			{Name: "employees", Type: proto.ColumnType_INT, Description: "Number of compromised employees."},
+			{Name: "users", Type: proto.ColumnType_INT, Description: "Number of compromised users."},
+			{Name: "last_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp of the most recent employee or user compromise."},
+			{Name: "days_since_last_compromise", Type: proto.ColumnType_INT, Description: "Number of days since the most recent employee or user compromise."},
+			{Name: "weak_password_percent", Type: proto.ColumnType_DOUBLE, Description: "Percentage of too weak and weak passwords, from employee statistics when available and user statistics otherwise."},
+			{Name: "high_risk_family_percent", Type: proto.ColumnType_DOUBLE, Description: "Percentage of stealers belonging to the high risk families of the risk model."},
+			{Name: "risk_model", Type: proto.ColumnType_JSON, Description: "Weights and parameters of the risk model used to compute the score."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Domain search response the score was computed from, as returned by the API without its URL lists."},
+		},
+	}
+}
+
+func listHudsonrockDomainRisk(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	domain := d.EqualsQuals["domain"].GetStringValue()
+	if domain == "" {
+		return nil, nil
+	}
+
+	model, err := newRiskModel(GetConfig(d.Connection).RiskModel)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_domain_risk.listHudsonrockDomainRisk", "config_error", err)
+		return nil, err
+	}
+
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_domain_risk.listHudsonrockDomainRisk", "config_error", err)
+		return nil, err
+	}
+	result, err := client.SearchByDomain(ctx, domain)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_domain_risk.listHudsonrockDomainRisk", "api_error", err)
+		return nil, err
+	}
+
+	d.StreamListItem(ctx, model.score(domain, result, timeNow()))
+	return nil, nil
+}
@@ -0,0 +1,301 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// Domain markers placed on the infection timeline.
+const (
+	timelineMarkerLastEmployeeCompromised = "last_employee_compromised"
+	timelineMarkerLastUserCompromised     = "last_user_compromised"
+)
+
+func tableHudsonrockInfectionTimeline(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_infection_timeline",
+		Description: "Infections found for a list of emails, IP addresses and usernames, bucketed by day, week or month of compromise.",
+		List: &plugin.ListConfig{
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "identifiers", Require: plugin.Optional},
+				{Name: "domains", Require: plugin.Optional},
+				{Name: "granularity", Require: plugin.Optional},
+			},
+			Hydrate: listHudsonrockInfectionTimeline,
+			Tags:    fanOutTags,
+		},
+		Columns: []*plugin.Column{
+			{Name: "identifiers", Type: proto.ColumnType_JSON, Description: "JSON array of emails, IP addresses and usernames looked up. Defaults to the emails, IP addresses and usernames on the connection's watchlist."},
+			{Name: "domains", Type: proto.ColumnType_JSON, Description: "JSON array of domains whose last employee and user compromises are marked on the timeline. Defaults to the domains on the connection's watchlist when identifiers is not specified."},
+			{Name: "granularity", Type: proto.ColumnType_STRING, Description: "Length of each period. Possible values are: day, week, month. Defaults to month."},
+			{Name: "period_start", Type: proto.ColumnType_TIMESTAMP, Description: "Start of the period, in UTC. Weeks start on Monday."},
+			{Name: "period_end", Type: proto.ColumnType_TIMESTAMP, Description: "End of the period, exclusive."},
+			{Name: "infection_count", Type: proto.ColumnType_INT, Description: "Number of distinct infections compromised during the period."},
+			{Name: "device_count", Type: proto.ColumnType_INT, Description: "Number of distinct devices compromised during the period, grouped with the connection's device_match_rules."},
+			{Name: "family_count", Type: proto.ColumnType_INT, Description: "Number of distinct stealer malware families seen during the period."},
+			{Name: "families", Type: proto.ColumnType_JSON, Description: "Distinct stealer malware families seen during the period. Only username lookups report families."},
+			{Name: "infection_ids", Type: proto.ColumnType_JSON, Description: "Infection IDs compromised during the period.", Transform: transform.FromField("InfectionIDs")},
+			{Name: "domain_markers", Type: proto.ColumnType_JSON, Description: "Last employee and user compromises of the domains that fall in the period.", Transform: transform.FromField("DomainMarkers").NullIfZero()},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Stealer objects of the infections compromised during the period as returned by the API, in the order of infection_ids."},
+		},
+	}
+}
+
+type InfectionTimelineBucket struct {
+	Identifiers    json.RawMessage
+	Domains        json.RawMessage
+	Granularity    string
+	PeriodStart    time.Time
+	PeriodEnd      time.Time
+	InfectionCount int
+	DeviceCount    int
+	FamilyCount    int
+	Families       []string
+	InfectionIDs   []string
+	DomainMarkers  []TimelineDomainMarker
+	Raw            []json.RawMessage
+}
+
+type TimelineDomainMarker struct {
+	Domain string    `json:"domain"`
+	Marker string    `json:"marker"`
+	Time   time.Time `json:"time"`
+}
+
+func listHudsonrockInfectionTimeline(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	config := GetConfig(d.Connection)
+
+	granularity := defaultTimelineGranularity
+	if d.EqualsQuals["granularity"] != nil {
+		granularity = d.EqualsQuals["granularity"].GetStringValue()
+	}
+	if err := validateTimelineGranularity(granularity); err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "qual_error", err)
+		return nil, err
+	}
+
+	rules, err := parseDeviceMatchRules(config.DeviceMatchRules)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "config_error", err)
+		return nil, err
+	}
+	recency, err := newRecencyPolicy(config, timeNow())
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "config_error", err)
+		return nil, err
+	}
+
+	var identifiers, domains []identifier
+	if d.EqualsQuals["identifiers"] != nil {
+		if identifiers, err = identifiersFromQual(d, "identifiers"); err != nil {
+			plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "qual_error", err)
+			return nil, err
+		}
+	}
+	if d.EqualsQuals["domains"] != nil {
+		values, err := stringsFromQual(d, "domains")
+		if err != nil {
+			plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "qual_error", err)
+			return nil, err
+		}
+		for _, value := range values {
+			domains = append(domains, identifier{assetTypeDomain, value})
+		}
+	}
+	if d.EqualsQuals["identifiers"] == nil && d.EqualsQuals["domains"] == nil {
+		for _, asset := range watchlistAssets(config.Watchlist) {
+			if asset.Type == assetTypeDomain {
+				domains = append(domains, asset)
+			} else {
+				identifiers = append(identifiers, asset)
+			}
+		}
+	}
+	if len(identifiers) == 0 && len(domains) == 0 {
+		return nil, nil
+	}
+
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "config_error", err)
+		return nil, err
+	}
+	limit := watchlistMaxConcurrency(config.Watchlist)
+	infections, err := lookupIdentifiers(ctx, client, recency, identifiers, limit)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "api_error", err)
+		return nil, err
+	}
+	markers, err := timelineDomainMarkers(ctx, client, recency, domains, limit)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "api_error", err)
+		return nil, err
+	}
+
+	buckets, err := buildInfectionTimeline(infections, markers, rules, granularity)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_infection_timeline.listHudsonrockInfectionTimeline", "timeline_error", err)
+		return nil, err
+	}
+	identifiersQual := qualOrValues(d, "identifiers", identifiers)
+	domainsQual := qualOrValues(d, "domains", domains)
+	for _, bucket := range buckets {
+		bucket.Identifiers = identifiersQual
+		bucket.Domains = domainsQual
+		d.StreamListItem(ctx, bucket)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// timelineDomainMarkers looks up the last employee and user compromises of
+// every domain, leaving out those before the since cutoff of recency. It fails
+// if any lookup fails.
+func timelineDomainMarkers(ctx context.Context, client *api.Client, recency recencyPolicy, domains []identifier, limit int) ([]TimelineDomainMarker, error) {
+	results := make([][]TimelineDomainMarker, len(domains))
+	errs := make([]error, len(domains))
+	forEachConcurrently(ctx, domains, limit, func(ctx context.Context, i int, domain identifier) {
+		result, err := client.SearchByDomain(ctx, domain.Value)
+		if err != nil {
+			errs[i] = fmt.Errorf("domain lookup for %q failed: %w", domain.Value, err)
+			return
+		}
+		for _, marker := range []struct {
+			name  string
+			value string
+		}{
+			{timelineMarkerLastEmployeeCompromised, result.LastEmployeeCompromised},
+			{timelineMarkerLastUserCompromised, result.LastUserCompromised},
+		} {
+			if t, ok := api.ParseDate(marker.value); ok && recency.include(marker.value) {
+				results[i] = append(results[i], TimelineDomainMarker{Domain: domain.Value, Marker: marker.name, Time: t})
+			}
+		}
+	})
+	for _, err := range errs {
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	var markers []TimelineDomainMarker
+	for _, result := range results {
+		markers = append(markers, result...)
+	}
+	return markers, nil
+}
+
+// buildInfectionTimeline buckets infections and domain markers by period.
+// Every period between the earliest and latest date gets a bucket, including
+// periods without any compromise. It fails if there are more than
+// maxTimelineBuckets of them. Infections and markers without a plausible date
+// are left out.
+func buildInfectionTimeline(infections []Infection, markers []TimelineDomainMarker, rules []deviceMatchRule, granularity string) ([]*InfectionTimelineBucket, error) {
+	now := timeNow()
+	devices := map[string]int{}
+	for n, group := range groupDevices(infections, rules) {
+		for _, infection := range group {
+			devices[infection.InfectionID] = n
+		}
+	}
+
+	type period struct {
+		bucket       *InfectionTimelineBucket
+		infectionIDs map[string]struct{}
+		raws         map[string]json.RawMessage
+		devices      map[int]struct{}
+		families     map[string]struct{}
+	}
+	periods := map[time.Time]*period{}
+	get := func(t time.Time) *period {
+		start := timelinePeriodStart(t, granularity)
+		p, ok := periods[start]
+		if !ok {
+			p = &period{
+				bucket:       &InfectionTimelineBucket{Granularity: granularity, PeriodStart: start, PeriodEnd: timelineNextPeriod(start, granularity)},
+				infectionIDs: map[string]struct{}{},
+				raws:         map[string]json.RawMessage{},
+				devices:      map[int]struct{}{},
+				families:     map[string]struct{}{},
+			}
+			periods[start] = p
+		}
+		return p
+	}
+
+	for _, infection := range infections {
+		t, ok := api.ParseDate(infection.DateCompromised)
+		if !ok || !plausibleTimelineDate(t, now) {
+			continue
+		}
+		p := get(t)
+		p.infectionIDs[infection.InfectionID] = struct{}{}
+		p.raws[infection.InfectionID] = infection.Raw
+		p.devices[devices[infection.InfectionID]] = struct{}{}
+		if infection.StealerFamily != "" {
+			p.families[infection.StealerFamily] = struct{}{}
+		}
+	}
+	for _, marker := range markers {
+		if !plausibleTimelineDate(marker.Time, now) {
+			continue
+		}
+		p := get(marker.Time)
+		p.bucket.DomainMarkers = append(p.bucket.DomainMarkers, marker)
+	}
+	if len(periods) == 0 {
+		return nil, nil
+	}
+
+	var starts []time.Time
+	for start := range periods {
+		starts = append(starts, start)
+	}
+	sort.Slice(starts, func(i, j int) bool { return starts[i].Before(starts[j]) })
+	first, last := starts[0], starts[len(starts)-1]
+	starts = timelineFill(first, last, granularity)
+	if starts == nil {
+		return nil, fmt.Errorf("the timeline from %s to %s has more than %d %s periods, use a coarser granularity", first.Format(time.DateOnly), last.Format(time.DateOnly), maxTimelineBuckets, granularity)
+	}
+
+	var buckets []*InfectionTimelineBucket
+	for _, start := range starts {
+		p := get(start)
+		p.bucket.InfectionIDs = sortedKeys(p.infectionIDs)
+		p.bucket.InfectionCount = len(p.infectionIDs)
+		p.bucket.Raw = infectionRaws(p.bucket.InfectionIDs, p.raws)
+		p.bucket.DeviceCount = len(p.devices)
+		p.bucket.Families = sortedKeys(p.families)
+		p.bucket.FamilyCount = len(p.families)
+		buckets = append(buckets, p.bucket)
+	}
+	return buckets, nil
+}
+
+// timelineFill returns the start of every period from first to last, or nil
+// if there are more than maxTimelineBuckets of them.
+func timelineFill(first, last time.Time, granularity string) []time.Time {
+	var starts []time.Time
+	for start := first; !start.After(last); start = timelineNextPeriod(start, granularity) {
+		if len(starts) == maxTimelineBuckets {
+			return nil
+		}
+		starts = append(starts, start)
+	}
+	return starts
+}
@@ -0,0 +1,145 @@
+package hudsonrock
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func tableHudsonrockNewInfection(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_new_infection",
+		Description: "Infections returned by email, IP or username lookups that are not yet part of the locally stored baseline.",
+		List: &plugin.ListConfig{
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "email", Require: plugin.AnyOf},
+				{Name: "ip", Require: plugin.AnyOf},
+				{Name: "username", Require: plugin.AnyOf},
+				{Name: "promote", Require: plugin.Optional},
+			},
+			Hydrate: listHudsonrockNewInfection,
+			Tags:    fanOutTags,
+		},
+		// Queries read and, with promote, write the baseline, so a result
+		// served from the cache would report infections promoted since or
+		// skip a promotion
+		Cache: &plugin.TableCacheOptions{Enabled: false},
+		Columns: []*plugin.Column{
+			// Postgres checks the quals again on every row, so the lookup
+			// columns hold the quals even for rows returned by another lookup
+			{Name: "email", Type: proto.ColumnType_STRING, Description: "Email searched.", Transform: transform.FromQual("email")},
+			{Name: "ip", Type: proto.ColumnType_STRING, Description: "IP address searched.", Transform: transform.FromQual("ip")},
+			{Name: "username", Type: proto.ColumnType_STRING, Description: "Username searched.", Transform: transform.FromQual("username")},
+			{Name: "promote", Type: proto.ColumnType_BOOL, Description: "If true, the new infections listed are added to the baseline once every row has been returned. Infections left out by a limit, or by a failed or cancelled query, stay new. Selecting from the table with this set writes to the baseline.", Transform: transform.FromQual("promote")},
+			{Name: "lookup_type", Type: proto.ColumnType_STRING, Description: "Lookup that returned the infection, the first in the order email, ip, username if several did. Possible values are: email, ip, username."},
+			{Name: "lookup_value", Type: proto.ColumnType_STRING, Description: "Email, IP address or username searched by the lookup that returned the infection."},
+			{Name: "infection_id", Type: proto.ColumnType_STRING, Description: "Stable fingerprint of the infected machine."},
+			{Name: "first_seen", Type: proto.ColumnType_TIMESTAMP, Description: "Time the infection was first returned by this table."},
+			{Name: "date_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp when the computer was compromised."},
+			{Name: "computer_name", Type: proto.ColumnType_STRING, Description: "Name of the infected computer."},
+			{Name: "operating_system", Type: proto.ColumnType_STRING, Description: "Operating system of the infected computer."},
+			{Name: "malware_path", Type: proto.ColumnType_STRING, Description: "File path of the detected malware on the infected computer."},
+			{Name: "stealer_ip", Type: proto.ColumnType_STRING, Description: "IP address of the infected computer.", Transform: transform.FromField("IP")},
+			{Name: "stealer_family", Type: proto.ColumnType_STRING, Description: "Stealer malware family. Only returned by username lookups."},
+			{Name: "antiviruses", Type: proto.ColumnType_JSON, Description: "List of antivirus products found on the infected computer."},
+			{Name: "top_logins", Type: proto.ColumnType_JSON, Description: "Top logins found on the infected computer."},
+			{Name: "total_corporate_services", Type: proto.ColumnType_INT, Description: "Corporate services found on the infected computer."},
+			{Name: "total_user_services", Type: proto.ColumnType_INT, Description: "User services found on the infected computer."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Stealer object as returned by the API, including fields that are not mapped to columns."},
+		},
+	}
+}
+
+type NewInfection struct {
+	Infection
+	FirstSeen time.Time
+}
+
+func listHudsonrockNewInfection(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	s, err := getStore(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_new_infection.listHudsonrockNewInfection", "store_error", err)
+		return nil, err
+	}
+	if s == nil {
+		return nil, errors.New("hudsonrock_new_infection requires snapshot_path to be set in the connection config")
+	}
+
+	recency, err := newRecencyPolicy(GetConfig(d.Connection), timeNow())
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_new_infection.listHudsonrockNewInfection", "config_error", err)
+		return nil, err
+	}
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_new_infection.listHudsonrockNewInfection", "config_error", err)
+		return nil, err
+	}
+
+	// Infections returned by several lookups are listed once
+	var infections []Infection
+	seen := map[string]struct{}{}
+	for _, lookupType := range []string{lookupTypeEmail, lookupTypeIP, lookupTypeUsername} {
+		value := d.EqualsQuals[lookupType].GetStringValue()
+		if value == "" {
+			continue
+		}
+		result, err := lookupInfections(ctx, client, recency, lookupType, value)
+		if err != nil {
+			plugin.Logger(ctx).Error("hudsonrock_new_infection.listHudsonrockNewInfection", "api_error", err, "lookup_type", lookupType)
+			return nil, err
+		}
+		for _, infection := range result {
+			if _, ok := seen[infection.InfectionID]; ok {
+				continue
+			}
+			seen[infection.InfectionID] = struct{}{}
+			infections = append(infections, infection)
+		}
+	}
+
+	ids := make([]string, 0, len(infections))
+	for _, infection := range infections {
+		ids = append(ids, infection.InfectionID)
+	}
+
+	now := timeNow()
+	firstSeen, err := s.RecordSightings(ctx, ids, now)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_new_infection.listHudsonrockNewInfection", "store_error", err)
+		return nil, err
+	}
+	known, err := s.InBaseline(ctx, ids)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_new_infection.listHudsonrockNewInfection", "store_error", err)
+		return nil, err
+	}
+
+	var listed []string
+	for _, infection := range infections {
+		if known[infection.InfectionID] {
+			continue
+		}
+		d.StreamListItem(ctx, &NewInfection{infection, firstSeen[infection.InfectionID]})
+		listed = append(listed, infection.InfectionID)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			break
+		}
+	}
+
+	// Only the infections listed are promoted, and only if the query was not
+	// cancelled, so that no infection leaves the new list unreported
+	if d.EqualsQuals["promote"].GetBoolValue() && ctx.Err() == nil {
+		if err := s.PromoteToBaseline(ctx, listed, now); err != nil {
+			plugin.Logger(ctx).Error("hudsonrock_new_infection.listHudsonrockNewInfection", "store_error", err)
+			return nil, err
+		}
+	}
+	return nil, nil
+}
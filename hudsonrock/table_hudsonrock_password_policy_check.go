@@ -0,0 +1,85 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableHudsonrockPasswordPolicyCheck(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_password_policy_check",
+		Description: "Evaluate a domain's compromised password strength statistics against the password policies configured on the connection.",
+		List: &plugin.ListConfig{
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "domain", Require: plugin.Required},
+			},
+			Hydrate: listHudsonrockPasswordPolicyCheck,
+		},
+		Columns: []*plugin.Column{
+			{Name: "domain", Type: proto.ColumnType_STRING, Description: "Domain evaluated."},
+			{Name: "policy", Type: proto.ColumnType_STRING, Description: "Name of the password policy."},
+			{Name: "population", Type: proto.ColumnType_STRING, Description: "Passwords the policy applies to. Possible values are: employees, users."},
+			{Name: "strengths", Type: proto.ColumnType_JSON, Description: "Password strength bins whose share is measured. Possible values are: too_weak, weak, medium, strong."},
+			{Name: "status", Type: proto.ColumnType_STRING, Description: "Outcome of the check. Possible values are: pass, fail, no_data."},
+			{Name: "passed", Type: proto.ColumnType_BOOL, Description: "True if the actual percentage is within the thresholds. Null when the domain has no password statistics for the population."},
+			{Name: "actual_percent", Type: proto.ColumnType_DOUBLE, Description: "Percentage of the population's passwords in the measured strength bins."},
+			{Name: "max_percent", Type: proto.ColumnType_DOUBLE, Description: "Highest percentage allowed by the policy."},
+			{Name: "min_percent", Type: proto.ColumnType_DOUBLE, Description: "Lowest percentage required by the policy."},
+			{Name: "total_passwords", Type: proto.ColumnType_INT, Description: "Number of compromised passwords of the population the statistics are based on."},
+			{Name: "reason", Type: proto.ColumnType_STRING, Description: "Explanation of the outcome."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Domain search response the policy was evaluated against, as returned by the API without its URL lists."},
+		},
+	}
+}
+
+type PasswordPolicyCheck struct {
+	Domain         string
+	Policy         string
+	Population     string
+	Strengths      []string
+	Status         string
+	Passed         *bool
+	ActualPercent  *float64
+	MaxPercent     *float64
+	MinPercent     *float64
+	TotalPasswords int
+	Reason         string
+	Raw            json.RawMessage
+}
+
+func listHudsonrockPasswordPolicyCheck(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	domain := d.EqualsQuals["domain"].GetStringValue()
+	if domain == "" {
+		return nil, nil
+	}
+
+	policies, err := newPasswordPolicies(GetConfig(d.Connection).PasswordPolicies)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_password_policy_check.listHudsonrockPasswordPolicyCheck", "config_error", err)
+		return nil, err
+	}
+
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_password_policy_check.listHudsonrockPasswordPolicyCheck", "config_error", err)
+		return nil, err
+	}
+	result, err := client.SearchByDomain(ctx, domain)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_password_policy_check.listHudsonrockPasswordPolicyCheck", "api_error", err)
+		return nil, err
+	}
+
+	for _, policy := range policies {
+		d.StreamListItem(ctx, policy.evaluate(domain, result))
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
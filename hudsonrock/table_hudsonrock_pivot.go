@@ -0,0 +1,217 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"strings"
+
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+const (
+	defaultPivotMaxDepth      = 2
+	defaultPivotRequestBudget = 20
+)
+
+// Relations between the nodes of the pivot graph.
+const (
+	pivotRelationInfectionIP    = "infection_ip"
+	pivotRelationInfectionLogin = "infection_login"
+)
+
+func tableHudsonrockPivot(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_pivot",
+		Description: "Walk from a seed email, IP address or username to related infections by looking up the IPs and logins found on each infected machine.",
+		List: &plugin.ListConfig{
+			KeyColumns: plugin.KeyColumnSlice{
+				{Name: "seed", Require: plugin.Required},
+				{Name: "max_depth", Require: plugin.Optional},
+				{Name: "request_budget", Require: plugin.Optional},
+			},
+			Hydrate: listHudsonrockPivot,
+			Tags:    fanOutTags,
+		},
+		Columns: []*plugin.Column{
+			{Name: "seed", Type: proto.ColumnType_STRING, Description: "Email, IP address or username the walk starts from."},
+			{Name: "max_depth", Type: proto.ColumnType_INT, Description: "Maximum number of hops from the seed. Defaults to 2."},
+			{Name: "request_budget", Type: proto.ColumnType_INT, Description: "Maximum number of API lookups made by the walk. Defaults to 20."},
+			{Name: "depth", Type: proto.ColumnType_INT, Description: "Number of hops from the seed to the source of the edge. The seed is at depth 0."},
+			{Name: "source_type", Type: proto.ColumnType_STRING, Description: "Type of the source identifier. Possible values are: email, ip, username."},
+			{Name: "source", Type: proto.ColumnType_STRING, Description: "Identifier that was looked up."},
+			{Name: "relation", Type: proto.ColumnType_STRING, Description: "How the target relates to the source. Possible values are: infection_ip, infection_login."},
+			{Name: "target_type", Type: proto.ColumnType_STRING, Description: "Type of the target identifier. Possible values are: email, ip, username."},
+			{Name: "target", Type: proto.ColumnType_STRING, Description: "Identifier found on the infection returned for the source."},
+			{Name: "target_visited", Type: proto.ColumnType_BOOL, Description: "True if the target was looked up during the walk."},
+			{Name: "infection_id", Type: proto.ColumnType_STRING, Description: "Stable fingerprint of the infection linking the source and target."},
+			{Name: "date_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp when the infected computer was compromised.", Transform: transform.FromField("DateCompromised").NullIfZero()},
+			{Name: "computer_name", Type: proto.ColumnType_STRING, Description: "Name of the infected computer."},
+			{Name: "budget_exhausted", Type: proto.ColumnType_BOOL, Description: "True if the walk stopped early because the request budget was used up."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Stealer object of the infection linking the source and target, as returned by the API."},
+		},
+	}
+}
+
+type PivotEdge struct {
+	Seed            string
+	MaxDepth        int
+	RequestBudget   int
+	Depth           int
+	SourceType      string
+	Source          string
+	Relation        string
+	TargetType      string
+	Target          string
+	TargetVisited   bool
+	InfectionID     string
+	DateCompromised string
+	ComputerName    string
+	BudgetExhausted bool
+	Raw             json.RawMessage
+}
+
+type pivotNode struct {
+	lookupType string
+	value      string
+	depth      int
+}
+
+func (n pivotNode) key() string {
+	return n.lookupType + ":" + strings.ToLower(n.value)
+}
+
+func listHudsonrockPivot(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	seed := strings.TrimSpace(d.EqualsQuals["seed"].GetStringValue())
+	if seed == "" {
+		return nil, nil
+	}
+	maxDepth := defaultPivotMaxDepth
+	if d.EqualsQuals["max_depth"] != nil {
+		maxDepth = int(d.EqualsQuals["max_depth"].GetInt64Value())
+	}
+	budget := defaultPivotRequestBudget
+	if d.EqualsQuals["request_budget"] != nil {
+		budget = int(d.EqualsQuals["request_budget"].GetInt64Value())
+	}
+	if maxDepth < 1 || budget < 1 {
+		return nil, fmt.Errorf("max_depth and request_budget must be greater than or equal to 1")
+	}
+
+	recency, err := newRecencyPolicy(GetConfig(d.Connection), timeNow())
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_pivot.listHudsonrockPivot", "config_error", err)
+		return nil, err
+	}
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_pivot.listHudsonrockPivot", "config_error", err)
+		return nil, err
+	}
+
+	start := pivotNode{identifierLookupType(seed), seed, 0}
+	queue := []pivotNode{start}
+	visited := map[string]bool{start.key(): true}
+	lookedUp := map[string]bool{}
+	seenEdges := map[string]bool{}
+	var edges []*PivotEdge
+	requests := 0
+	exhausted := false
+
+	for len(queue) > 0 {
+		node := queue[0]
+		queue = queue[1:]
+
+		if requests >= budget {
+			exhausted = true
+			break
+		}
+		requests++
+		lookedUp[node.key()] = true
+
+		infections, err := lookupInfections(ctx, client, recency, node.lookupType, node.value)
+		if err != nil {
+			if node.depth == 0 {
+				plugin.Logger(ctx).Error("hudsonrock_pivot.listHudsonrockPivot", "api_error", err, "seed", seed)
+				return nil, err
+			}
+			plugin.Logger(ctx).Warn("hudsonrock_pivot.listHudsonrockPivot", "api_error", err, "node", node.value)
+			continue
+		}
+
+		for _, infection := range infections {
+			for _, target := range pivotTargets(infection) {
+				if strings.EqualFold(target.value, node.value) {
+					continue
+				}
+				target.depth = node.depth + 1
+
+				edgeKey := strings.Join([]string{node.key(), target.relation, target.key(), infection.InfectionID}, "|")
+				if seenEdges[edgeKey] {
+					continue
+				}
+				seenEdges[edgeKey] = true
+
+				edges = append(edges, &PivotEdge{
+					Depth:           node.depth,
+					SourceType:      node.lookupType,
+					Source:          node.value,
+					Relation:        target.relation,
+					TargetType:      target.lookupType,
+					Target:          target.value,
+					InfectionID:     infection.InfectionID,
+					DateCompromised: infection.DateCompromised,
+					ComputerName:    infection.ComputerName,
+					Raw:             infection.Raw,
+				})
+
+				if target.depth < maxDepth && !visited[target.key()] {
+					visited[target.key()] = true
+					queue = append(queue, target.pivotNode)
+				}
+			}
+		}
+	}
+
+	for _, edge := range edges {
+		edge.Seed = seed
+		edge.MaxDepth = maxDepth
+		edge.RequestBudget = budget
+		edge.TargetVisited = lookedUp[pivotNode{edge.TargetType, edge.Target, 0}.key()]
+		edge.BudgetExhausted = exhausted
+		d.StreamListItem(ctx, edge)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+type pivotTarget struct {
+	pivotNode
+	relation string
+}
+
+// pivotTargets returns the identifiers found on an infection that can be
+// looked up in turn: its IP address and its unmasked logins.
+func pivotTargets(infection Infection) []pivotTarget {
+	var targets []pivotTarget
+	if ip := strings.TrimSpace(infection.IP); net.ParseIP(ip) != nil {
+		targets = append(targets, pivotTarget{pivotNode{lookupType: lookupTypeIP, value: ip}, pivotRelationInfectionIP})
+	}
+	for _, login := range infection.TopLogins {
+		login = strings.TrimSpace(login)
+		// Hudson Rock masks part of most logins, which makes them useless
+		// for further lookups.
+		if login == "" || strings.Contains(login, "*") {
+			continue
+		}
+		targets = append(targets, pivotTarget{pivotNode{lookupType: identifierLookupType(login), value: login}, pivotRelationInfectionLogin})
+	}
+	return targets
+}
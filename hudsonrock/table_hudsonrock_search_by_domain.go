@@ -31,7 +31,8 @@ func tableHudsonrockSearchByDomain(_ context.Context) *plugin.Table {
 			{Name: "is_shopify", Type: proto.ColumnType_BOOL, Description: "Indicates if the domain is a Shopify store."},
 			{Name: "last_employee_compromised", Type: proto.ColumnType_STRING, Description: "Timestamp of the last employee compromise for the domain."},
 			{Name: "last_user_compromised", Type: proto.ColumnType_STRING, Description: "Timestamp of the last user compromise for the domain."},
-			{Name: "antiviruses", Type: proto.ColumnType_JSON, Description: "Antivirus statistics and list of antivirus products found in the dataset."},
+			{Name: "antiviruses", Type: proto.ColumnType_JSON, Description: "Antivirus statistics and list of antivirus products found in the dataset, as returned by the API. See antivirus_products for their vendors."},
+			{Name: "antivirus_products", Type: proto.ColumnType_JSON, Description: "Antivirus products found in the dataset with their canonical vendor and product class (consumer_av, enterprise_epp, edr or unknown).", Transform: transform.FromField("Antiviruses.List").Transform(antivirusProductVendors)},
 			{Name: "applications", Type: proto.ColumnType_JSON, Description: "List of detected application keywords related to the domain."},
 			{Name: "employee_passwords", Type: proto.ColumnType_JSON, Description: "Password strength statistics for employees of the domain."},
 			{Name: "user_passwords", Type: proto.ColumnType_JSON, Description: "Password strength statistics for users of the domain."},
@@ -40,6 +41,8 @@ func tableHudsonrockSearchByDomain(_ context.Context) *plugin.Table {
 			{Name: "employees_urls", Type: proto.ColumnType_JSON, Description: "List of URLs associated with employees for the given domain.", Transform: transform.FromField("Data.EmployeesURLs")},
 			{Name: "clients_urls", Type: proto.ColumnType_JSON, Description: "List of URLs associated with clients for the given domain.", Transform: transform.FromField("Data.ClientsURLs")},
 			{Name: "all_urls", Type: proto.ColumnType_JSON, Description: "List of all URLs (employees and clients) associated with the given domain.", Transform: transform.FromField("Data.AllURLs")},
+			{Name: "truncated", Type: proto.ColumnType_BOOL, Description: "True if URLs were left out of the URL lists because the response is larger than max_response_bytes."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Response as returned by the API without its URL lists, including fields that are not mapped to columns."},
 		},
 	}
 }
@@ -50,12 +53,17 @@ func listHudsonrockSearchByDomain(ctx context.Context, d *plugin.QueryData, _ *p
 		return nil, nil
 	}
 
-	client := NewClient(ctx, d)
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_search_by_domain.listHudsonrockSearchByDomain", "config_error", err)
+		return nil, err
+	}
 	result, err := client.SearchByDomain(ctx, domain)
 	if err != nil {
 		plugin.Logger(ctx).Error("hudsonrock_search_by_domain.listHudsonrockSearchByDomain", "api_error", err)
 		return nil, err
 	}
+	recordDomainSnapshot(ctx, d, domain, result)
 
 	d.StreamListItem(ctx, result)
 	return nil, nil
@@ -21,18 +21,39 @@ func tableHudsonrockSearchByEmail(_ context.Context) *plugin.Table {
 		},
 		Columns: []*plugin.Column{
 			{Name: "email", Type: proto.ColumnType_STRING, Description: "Email searched.", Transform: transform.FromQual("email")},
+			{Name: "email_domain", Type: proto.ColumnType_STRING, Description: "Domain part of the email searched.", Transform: transform.FromQual("email").Transform(emailDomain)},
+			{Name: "is_freemail", Type: proto.ColumnType_BOOL, Description: "True if the email searched belongs to a free or disposable mail provider.", Transform: transform.FromQual("email").Transform(isFreemail)},
 			{Name: "message", Type: proto.ColumnType_STRING, Description: "API message about the email."},
+			{Name: "infection_id", Type: proto.ColumnType_STRING, Description: "Stable fingerprint of the infected machine, derived from the computer name, compromise date, malware path and IP address. The same infection has the same ID across lookup tables.", Transform: transform.FromField("Stealer").Transform(infectionID)},
 			{Name: "stealer_total_corporate_services", Type: proto.ColumnType_INT, Description: "Stealer total corporate services found.", Transform: transform.FromField("Stealer.TotalCorporateServices")},
 			{Name: "stealer_total_user_services", Type: proto.ColumnType_INT, Description: "Stealer total user services found.", Transform: transform.FromField("Stealer.TotalUserServices")},
 			{Name: "date_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp when the computer was compromised.", Transform: transform.FromField("Stealer.DateCompromised")},
+			{Name: "days_since_compromise", Type: proto.ColumnType_INT, Description: "Number of whole days since the computer was compromised."},
+			{Name: "recency_class", Type: proto.ColumnType_STRING, Description: "Age class of the compromise, with boundaries set by the connection's recency block. Possible values are: critical, recent, aging, stale.", Transform: transform.FromField("RecencyClass").NullIfZero()},
 			{Name: "computer_name", Type: proto.ColumnType_STRING, Description: "Name of the infected computer.", Transform: transform.FromField("Stealer.ComputerName")},
 			{Name: "operating_system", Type: proto.ColumnType_STRING, Description: "Operating system of the infected computer.", Transform: transform.FromField("Stealer.OperatingSystem")},
+			{Name: "os_family", Type: proto.ColumnType_STRING, Description: "Operating system family of the infected computer. Possible values are: windows, windows_server, macos, linux, android, ios, chromeos, other.", Transform: transform.FromField("Stealer.OperatingSystem").TransformP(operatingSystemComponent, "Family")},
+			{Name: "os_version", Type: proto.ColumnType_STRING, Description: "Operating system version of the infected computer, e.g. 10, 11 or 13.4.", Transform: transform.FromField("Stealer.OperatingSystem").TransformP(operatingSystemComponent, "Version")},
+			{Name: "os_edition", Type: proto.ColumnType_STRING, Description: "Operating system edition of the infected computer, e.g. Home, Pro or Enterprise.", Transform: transform.FromField("Stealer.OperatingSystem").TransformP(operatingSystemComponent, "Edition")},
+			{Name: "os_architecture", Type: proto.ColumnType_STRING, Description: "Processor architecture of the infected computer, e.g. x64, x86 or arm64.", Transform: transform.FromField("Stealer.OperatingSystem").TransformP(operatingSystemComponent, "Architecture")},
+			{Name: "is_enterprise_edition", Type: proto.ColumnType_BOOL, Description: "True if the infected computer runs an enterprise, education or server edition of Windows.", Transform: transform.FromField("Stealer.OperatingSystem").TransformP(operatingSystemComponent, "IsEnterpriseEdition")},
 			{Name: "malware_path", Type: proto.ColumnType_STRING, Description: "File path of the detected malware on the infected computer.", Transform: transform.FromField("Stealer.MalwarePath")},
+			{Name: "malware_drive", Type: proto.ColumnType_STRING, Description: "Drive letter of the malware path, e.g. C:.", Transform: transform.FromField("Stealer.MalwarePath").TransformP(malwarePathComponent, "Drive")},
+			{Name: "malware_directory", Type: proto.ColumnType_STRING, Description: "Directory the malware was executed from.", Transform: transform.FromField("Stealer.MalwarePath").TransformP(malwarePathComponent, "Directory")},
+			{Name: "malware_filename", Type: proto.ColumnType_STRING, Description: "File name of the malware executable.", Transform: transform.FromField("Stealer.MalwarePath").TransformP(malwarePathComponent, "Filename")},
+			{Name: "malware_extension", Type: proto.ColumnType_STRING, Description: "Lower-cased file extension of the malware executable.", Transform: transform.FromField("Stealer.MalwarePath").TransformP(malwarePathComponent, "Extension")},
+			{Name: "malware_profile_user", Type: proto.ColumnType_STRING, Description: "User profile name found in the malware path.", Transform: transform.FromField("Stealer.MalwarePath").TransformP(malwarePathComponent, "ProfileUser")},
+			{Name: "malware_location_class", Type: proto.ColumnType_STRING, Description: "Class of folder the malware ran from. Possible values are: temp, downloads, appdata, program_files, other.", Transform: transform.FromField("Stealer.MalwarePath").TransformP(malwarePathComponent, "LocationClass")},
 			{Name: "antiviruses", Type: proto.ColumnType_JSON, Description: "List of antivirus products found on the infected computer.", Transform: transform.FromField("Stealer.Antiviruses")},
+			{Name: "antivirus_vendors", Type: proto.ColumnType_JSON, Description: "Distinct canonical vendors of the antivirus products found on the infected computer.", Transform: transform.FromField("Stealer.Antiviruses").Transform(antivirusVendors)},
+			{Name: "has_edr", Type: proto.ColumnType_BOOL, Description: "True if an endpoint detection and response (EDR) product was found on the infected computer.", Transform: transform.FromField("Stealer.Antiviruses").Transform(hasEDR)},
+			{Name: "defender_only", Type: proto.ColumnType_BOOL, Description: "True if Microsoft Defender was the only antivirus product found on the infected computer.", Transform: transform.FromField("Stealer.Antiviruses").Transform(defenderOnly)},
 			{Name: "top_passwords", Type: proto.ColumnType_JSON, Description: "Top passwords found on the infected computer.", Transform: transform.FromField("Stealer.TopPasswords")},
 			{Name: "top_logins", Type: proto.ColumnType_JSON, Description: "Top logins found on the infected computer.", Transform: transform.FromField("Stealer.TopLogins")},
+			{Name: "corporate_login_domains", Type: proto.ColumnType_JSON, Description: "Distinct domains of the top logins that do not belong to a free or disposable mail provider.", Transform: transform.FromField("Stealer.TopLogins").Transform(corporateLoginDomains)},
 			{Name: "total_corporate_services", Type: proto.ColumnType_INT, Description: "Total corporate services found."},
 			{Name: "total_user_services", Type: proto.ColumnType_INT, Description: "Total user services found."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Stealer object as returned by the API, including fields that are not mapped to columns.", Transform: transform.FromField("Stealer.Raw")},
 		},
 	}
 }
@@ -42,6 +63,8 @@ type EmailDetails struct {
 	TotalCorporateServices int
 	TotalUserServices      int
 	Stealer                api.EmailStealer
+	DaysSinceCompromise    *int
+	RecencyClass           string
 }
 
 func listHudsonrockSearchByEmail(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
@@ -51,7 +74,17 @@ func listHudsonrockSearchByEmail(ctx context.Context, d *plugin.QueryData, _ *pl
 		return nil, nil
 	}
 
-	client := NewClient(ctx, d)
+	recency, err := newRecencyPolicy(GetConfig(d.Connection), timeNow())
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_search_by_email.listHudsonrockSearchByEmail", "config_error", err)
+		return nil, err
+	}
+
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_search_by_email.listHudsonrockSearchByEmail", "config_error", err)
+		return nil, err
+	}
 	output, err := client.SearchByEmail(ctx, email)
 	if err != nil {
 		plugin.Logger(ctx).Error("hudsonrock_search_by_email.listHudsonrockSearchByEmail", "api_error", err)
@@ -59,7 +92,17 @@ func listHudsonrockSearchByEmail(ctx context.Context, d *plugin.QueryData, _ *pl
 	}
 
 	for _, result := range output.Stealers {
-		d.StreamListItem(ctx, &EmailDetails{output.Message, output.TotalCorporateServices, output.TotalUserServices, result})
+		if !recency.include(result.DateCompromised) {
+			continue
+		}
+		row := &EmailDetails{
+			Message:                output.Message,
+			TotalCorporateServices: int(output.TotalCorporateServices),
+			TotalUserServices:      int(output.TotalUserServices),
+			Stealer:                result,
+		}
+		row.DaysSinceCompromise, row.RecencyClass = recency.classify(result.DateCompromised)
+		d.StreamListItem(ctx, row)
 
 		// Context can be cancelled due to manual cancellation or the limit has been hit
 		if d.RowsRemaining(ctx) == 0 {
@@ -24,6 +24,8 @@ func tableHudsonrockUrlByDomain(_ context.Context) *plugin.Table {
 			{Name: "employees_urls", Type: proto.ColumnType_JSON, Description: "List of URLs associated with employees for the given domain.", Transform: transform.FromField("Data.EmployeesURLs")},
 			{Name: "clients_urls", Type: proto.ColumnType_JSON, Description: "List of URLs associated with clients for the given domain.", Transform: transform.FromField("Data.ClientsURLs")},
 			{Name: "all_urls", Type: proto.ColumnType_JSON, Description: "List of all URLs (employees and clients) associated with the given domain.", Transform: transform.FromField("Data.AllURLs")},
+			{Name: "truncated", Type: proto.ColumnType_BOOL, Description: "True if URLs were left out of the URL lists because the response is larger than max_response_bytes."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Response as returned by the API without its URL lists, nor the data object when it only holds URL lists, including fields that are not mapped to columns."},
 		},
 	}
 }
@@ -34,7 +36,11 @@ func listHudsonrockUrlByDomain(ctx context.Context, d *plugin.QueryData, _ *plug
 		return nil, nil
 	}
 
-	client := NewClient(ctx, d)
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_url_by_domain.listHudsonrockUrlByDomain", "config_error", err)
+		return nil, err
+	}
 	result, err := client.UrlByDomain(ctx, domain)
 	if err != nil {
 		plugin.Logger(ctx).Error("hudsonrock_url_by_domain.listHudsonrockUrlByDomain", "api_error", err)
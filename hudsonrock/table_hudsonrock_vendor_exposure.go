@@ -0,0 +1,155 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+func tableHudsonrockVendorExposure(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_vendor_exposure",
+		Description: "Ranked infostealer exposure of the third-party vendor domains configured on the connection.",
+		List: &plugin.ListConfig{
+			Hydrate: listHudsonrockVendorExposure,
+			Tags:    fanOutTags,
+		},
+		Columns: []*plugin.Column{
+			{Name: "domain", Type: proto.ColumnType_STRING, Description: "Vendor domain."},
+			{Name: "vendor_name", Type: proto.ColumnType_STRING, Description: "Vendor name, if given in vendors_file.", Transform: transform.FromField("Name")},
+			{Name: "status", Type: proto.ColumnType_STRING, Description: "Lookup status. Possible values are: ok, error."},
+			{Name: "error", Type: proto.ColumnType_STRING, Description: "Reason the lookup failed when the status is error."},
+			{Name: "employees", Type: proto.ColumnType_INT, Description: "Number of compromised vendor employees."},
+			{Name: "users", Type: proto.ColumnType_INT, Description: "Number of compromised users of the vendor's services."},
+			{Name: "third_parties", Type: proto.ColumnType_INT, Description: "Number of third parties."},
+			{Name: "total_stealers", Type: proto.ColumnType_INT, Description: "Total stealers found."},
+			{Name: "exposure", Type: proto.ColumnType_INT, Description: "Number of compromised employees and users, used to rank vendors."},
+			{Name: "exposure_rank", Type: proto.ColumnType_INT, Description: "Rank of the vendor's exposure within the portfolio, 1 being the most exposed. Vendors with equal exposure share a rank."},
+			{Name: "percentile_rank", Type: proto.ColumnType_DOUBLE, Description: "Percentage of the other successfully looked up vendors in the portfolio with a lower exposure than this vendor, or 0 if it is the only one."},
+			{Name: "last_employee_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp of the last vendor employee compromise.", Transform: transform.FromField("LastEmployeeCompromised").NullIfZero()},
+			{Name: "last_user_compromised", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp of the last compromise of a user of the vendor's services.", Transform: transform.FromField("LastUserCompromised").NullIfZero()},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "Domain search response for the vendor, as returned by the API without its URL lists."},
+		},
+	}
+}
+
+type VendorExposure struct {
+	Domain                  string
+	Name                    string
+	Status                  string
+	Error                   string
+	Employees               *int
+	Users                   *int
+	ThirdParties            *int
+	TotalStealers           *int64
+	Exposure                *int
+	ExposureRank            *int
+	PercentileRank          *float64
+	LastEmployeeCompromised string
+	LastUserCompromised     string
+	Raw                     json.RawMessage
+}
+
+func listHudsonrockVendorExposure(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	config := GetConfig(d.Connection)
+	vendors, err := loadVendors(config)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_vendor_exposure.listHudsonrockVendorExposure", "config_error", err)
+		return nil, err
+	}
+	if len(vendors) == 0 {
+		return nil, nil
+	}
+
+	// Lookups share the hudsonrock_fan_out rate limiter, so the concurrency only
+	// bounds the number of requests waiting on it.
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_vendor_exposure.listHudsonrockVendorExposure", "config_error", err)
+		return nil, err
+	}
+	rows := make([]*VendorExposure, len(vendors))
+	forEachConcurrently(ctx, vendors, watchlistMaxConcurrency(config.Watchlist), func(ctx context.Context, i int, v vendor) {
+		rows[i] = vendorExposure(ctx, client, v)
+	})
+	rankVendorExposure(rows)
+
+	for _, row := range rows {
+		if row == nil {
+			continue
+		}
+		d.StreamListItem(ctx, row)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// vendorExposure looks up a single vendor domain. Lookup errors are reported
+// in the row rather than aborting the portfolio.
+func vendorExposure(ctx context.Context, client *api.Client, v vendor) *VendorExposure {
+	row := &VendorExposure{Domain: v.Domain, Name: v.Name}
+
+	result, err := client.SearchByDomain(ctx, v.Domain)
+	if err != nil {
+		plugin.Logger(ctx).Warn("hudsonrock_vendor_exposure.vendorExposure", "api_error", err, "domain", v.Domain)
+		row.Status, row.Error = "error", err.Error()
+		return row
+	}
+
+	employees, users, thirdParties := int(result.Employees), int(result.Users), int(result.ThirdParties)
+	totalStealers := int64(result.TotalStealers)
+	exposure := employees + users
+	row.Status = "ok"
+	row.Employees = &employees
+	row.Users = &users
+	row.ThirdParties = &thirdParties
+	row.TotalStealers = &totalStealers
+	row.Exposure = &exposure
+	row.LastEmployeeCompromised = result.LastEmployeeCompromised
+	row.LastUserCompromised = result.LastUserCompromised
+	row.Raw = result.Raw
+	return row
+}
+
+// rankVendorExposure sets the exposure rank and percentile rank of every
+// successfully looked up vendor.
+func rankVendorExposure(rows []*VendorExposure) {
+	var ranked []*VendorExposure
+	for _, row := range rows {
+		if row != nil && row.Exposure != nil {
+			ranked = append(ranked, row)
+		}
+	}
+	sort.SliceStable(ranked, func(i, j int) bool {
+		return *ranked[i].Exposure > *ranked[j].Exposure
+	})
+
+	for i, row := range ranked {
+		rank := i + 1
+		if i > 0 && *ranked[i-1].Exposure == *row.Exposure {
+			rank = *ranked[i-1].ExposureRank
+		}
+		row.ExposureRank = &rank
+
+		lower := 0
+		for _, other := range ranked {
+			if *other.Exposure < *row.Exposure {
+				lower++
+			}
+		}
+		percentile := 0.0
+		if len(ranked) > 1 {
+			percentile = round2(float64(lower) / float64(len(ranked)-1) * 100)
+		}
+		row.PercentileRank = &percentile
+	}
+}
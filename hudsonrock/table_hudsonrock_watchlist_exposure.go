@@ -0,0 +1,141 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+	"sort"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+func tableHudsonrockWatchlistExposure(_ context.Context) *plugin.Table {
+	return &plugin.Table{
+		Name:        "hudsonrock_watchlist_exposure",
+		Description: "Exposure summary for every email, domain, IP address and username on the connection's watchlist.",
+		List: &plugin.ListConfig{
+			Hydrate: listHudsonrockWatchlistExposure,
+			Tags:    fanOutTags,
+		},
+		Columns: []*plugin.Column{
+			{Name: "asset_type", Type: proto.ColumnType_STRING, Description: "Type of the watched asset. Possible values are: email, domain, ip, username."},
+			{Name: "asset", Type: proto.ColumnType_STRING, Description: "Watched email, domain, IP address or username."},
+			{Name: "status", Type: proto.ColumnType_STRING, Description: "Exposure status of the asset. Possible values are: exposed, clean, error."},
+			{Name: "infection_count", Type: proto.ColumnType_INT, Description: "Number of infections found for the asset. For domains, the number of compromised employees and users."},
+			{Name: "latest_compromise", Type: proto.ColumnType_TIMESTAMP, Description: "Timestamp of the most recent compromise of the asset."},
+			{Name: "families", Type: proto.ColumnType_JSON, Description: "Distinct stealer malware families found for the asset. Email and IP lookups do not report families."},
+			{Name: "error", Type: proto.ColumnType_STRING, Description: "Error returned by the lookup when the status is error."},
+			{Name: "raw", Type: proto.ColumnType_JSON, Description: "What the lookup returned: for domains, the domain search response as returned by the API without its URL lists; for other assets, the array of stealer objects found."},
+		},
+	}
+}
+
+type WatchlistExposure struct {
+	AssetType        string
+	Asset            string
+	Status           string
+	InfectionCount   int
+	LatestCompromise *time.Time
+	Families         []string
+	Error            string
+	Raw              json.RawMessage
+}
+
+func listHudsonrockWatchlistExposure(ctx context.Context, d *plugin.QueryData, _ *plugin.HydrateData) (interface{}, error) {
+	config := GetConfig(d.Connection)
+	assets := watchlistAssets(config.Watchlist)
+	if len(assets) == 0 {
+		return nil, nil
+	}
+
+	recency, err := newRecencyPolicy(config, timeNow())
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_watchlist_exposure.listHudsonrockWatchlistExposure", "config_error", err)
+		return nil, err
+	}
+	client, err := NewClient(ctx, d)
+	if err != nil {
+		plugin.Logger(ctx).Error("hudsonrock_watchlist_exposure.listHudsonrockWatchlistExposure", "config_error", err)
+		return nil, err
+	}
+	rows := make([]*WatchlistExposure, len(assets))
+	forEachConcurrently(ctx, assets, watchlistMaxConcurrency(config.Watchlist), func(ctx context.Context, i int, asset identifier) {
+		rows[i] = watchlistExposure(ctx, client, recency, asset)
+	})
+
+	for _, row := range rows {
+		if row == nil {
+			continue
+		}
+		d.StreamListItem(ctx, row)
+
+		// Context can be cancelled due to manual cancellation or the limit has been hit
+		if d.RowsRemaining(ctx) == 0 {
+			return nil, nil
+		}
+	}
+	return nil, nil
+}
+
+// watchlistExposure looks up a single watched asset. Lookup errors are
+// reported in the row rather than failing the whole table. The since cutoff
+// of recency only applies to email, IP and username assets, as domain
+// lookups only return totals.
+func watchlistExposure(ctx context.Context, client *api.Client, recency recencyPolicy, asset identifier) *WatchlistExposure {
+	row := &WatchlistExposure{AssetType: asset.Type, Asset: asset.Value}
+
+	families := map[string]struct{}{}
+	var dates []string
+
+	if asset.Type == assetTypeDomain {
+		result, err := client.SearchByDomain(ctx, asset.Value)
+		if err != nil {
+			plugin.Logger(ctx).Warn("hudsonrock_watchlist_exposure.watchlistExposure", "api_error", err, "asset", asset.Value)
+			row.Status, row.Error = "error", err.Error()
+			return row
+		}
+		row.InfectionCount = int(result.Employees + result.Users)
+		row.Raw = result.Raw
+		dates = append(dates, result.LastEmployeeCompromised, result.LastUserCompromised)
+		for family, count := range result.StealerFamilies {
+			if count > 0 {
+				families[family] = struct{}{}
+			}
+		}
+	} else {
+		infections, err := lookupInfections(ctx, client, recency, asset.Type, asset.Value)
+		if err != nil {
+			plugin.Logger(ctx).Warn("hudsonrock_watchlist_exposure.watchlistExposure", "api_error", err, "asset", asset.Value)
+			row.Status, row.Error = "error", err.Error()
+			return row
+		}
+		row.InfectionCount = len(infections)
+		raws := make([]json.RawMessage, 0, len(infections))
+		for _, infection := range infections {
+			raws = append(raws, infection.Raw)
+			dates = append(dates, infection.DateCompromised)
+			if infection.StealerFamily != "" {
+				families[infection.StealerFamily] = struct{}{}
+			}
+		}
+		row.Raw, _ = json.Marshal(raws)
+	}
+
+	for _, date := range dates {
+		if t, ok := api.ParseDate(date); ok && (row.LatestCompromise == nil || t.After(*row.LatestCompromise)) {
+			row.LatestCompromise = &t
+		}
+	}
+	for family := range families {
+		row.Families = append(row.Families, family)
+	}
+	sort.Strings(row.Families)
+
+	row.Status = "clean"
+	if row.InfectionCount > 0 {
+		row.Status = "exposed"
+	}
+	return row
+}
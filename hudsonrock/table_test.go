@@ -0,0 +1,368 @@
+package hudsonrock
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api/hudsonrocktest"
+	"github.com/turbot/steampipe-plugin-sdk/v5/anywhere"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc"
+	"github.com/turbot/steampipe-plugin-sdk/v5/grpc/proto"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin"
+)
+
+var update = flag.Bool("update", false, "rewrite the golden files in testdata with the rows the tables return")
+
+// testNow is the time the table tests run at, a few months after the
+// compromises in the mock server fixtures.
+var testNow = time.Date(2024, time.October, 1, 0, 0, 0, 0, time.UTC)
+
+const testConnection = "hudsonrock"
+
+// tableQuery is a query of a single table, with its quals given as Go
+// values: strings, ints, bools, or json.RawMessage for JSONB columns.
+type tableQuery struct {
+	table string
+	quals map[string]any
+}
+
+type tableTest struct {
+	// name is also the name of the golden file in testdata
+	name  string
+	query tableQuery
+	// config is added to the connection block, after base_url
+	config string
+	// setup queries run before the query under test, e.g. to record
+	// snapshots
+	setup []tableQuery
+}
+
+var tableTests = []tableTest{
+	{
+		name:  "search_by_email",
+		query: tableQuery{"hudsonrock_search_by_email", map[string]any{"email": "jane.doe@example.com"}},
+	},
+	{
+		name:   "search_by_email_since",
+		query:  tableQuery{"hudsonrock_search_by_email", map[string]any{"email": "jane.doe@example.com"}},
+		config: `since = "2024-01-01"`,
+	},
+	{
+		name:  "search_by_ip",
+		query: tableQuery{"hudsonrock_search_by_ip", map[string]any{"ip": "192.0.2.10"}},
+	},
+	{
+		name:  "search_by_username",
+		query: tableQuery{"hudsonrock_search_by_username", map[string]any{"username": "jdoe"}},
+	},
+	{
+		name:  "search_by_domain",
+		query: tableQuery{"hudsonrock_search_by_domain", map[string]any{"domain": "example.com"}},
+	},
+	{
+		name:  "url_by_domain",
+		query: tableQuery{"hudsonrock_url_by_domain", map[string]any{"domain": "example.com"}},
+	},
+	{
+		name:  "domain_risk",
+		query: tableQuery{"hudsonrock_domain_risk", map[string]any{"domain": "example.com"}},
+	},
+	{
+		name:  "password_policy_check",
+		query: tableQuery{"hudsonrock_password_policy_check", map[string]any{"domain": "example.com"}},
+	},
+	{
+		name:  "device",
+		query: tableQuery{"hudsonrock_device", map[string]any{"identifiers": json.RawMessage(`["jane.doe@example.com", "jdoe", "192.0.2.10"]`)}},
+	},
+	{
+		name:   "device_since",
+		query:  tableQuery{"hudsonrock_device", map[string]any{"identifiers": json.RawMessage(`["jane.doe@example.com", "jdoe", "192.0.2.10"]`)}},
+		config: `since = "2024-01-01"`,
+	},
+	{
+		name: "infection_timeline",
+		query: tableQuery{"hudsonrock_infection_timeline", map[string]any{
+			"identifiers": json.RawMessage(`["jane.doe@example.com", "jdoe"]`),
+			"domains":     json.RawMessage(`["example.com"]`),
+			"granularity": "month",
+		}},
+	},
+	{
+		name:  "pivot",
+		query: tableQuery{"hudsonrock_pivot", map[string]any{"seed": "jane.doe@example.com", "max_depth": int64(1)}},
+	},
+	{
+		name:  "watchlist_exposure",
+		query: tableQuery{"hudsonrock_watchlist_exposure", nil},
+		config: `watchlist {
+    emails  = ["jane.doe@example.com"]
+    domains = ["example.com"]
+    ips     = ["192.0.2.10"]
+  }`,
+	},
+	{
+		name:  "watchlist_exposure_since",
+		query: tableQuery{"hudsonrock_watchlist_exposure", nil},
+		config: `since = "2024-01-01"
+  watchlist {
+    emails  = ["jane.doe@example.com"]
+    domains = ["example.com"]
+    ips     = ["192.0.2.10"]
+  }`,
+	},
+	{
+		name:   "vendor_exposure",
+		query:  tableQuery{"hudsonrock_vendor_exposure", nil},
+		config: `vendors = ["example.com", "supplier.example"]`,
+	},
+	{
+		name:   "new_infection",
+		query:  tableQuery{"hudsonrock_new_infection", map[string]any{"email": "jane.doe@example.com"}},
+		config: `snapshot_path = "{{snapshot_path}}"`,
+	},
+	{
+		// DESKTOP-7H2KQ1 is returned by all three lookups
+		name:   "new_infection_lookups",
+		query:  tableQuery{"hudsonrock_new_infection", map[string]any{"email": "jane.doe@example.com", "ip": "192.0.2.10", "username": "jdoe"}},
+		config: `snapshot_path = "{{snapshot_path}}"`,
+	},
+	{
+		name:   "new_infection_promoted",
+		query:  tableQuery{"hudsonrock_new_infection", map[string]any{"email": "jane.doe@example.com", "username": "jdoe"}},
+		config: `snapshot_path = "{{snapshot_path}}"`,
+		setup: []tableQuery{
+			{"hudsonrock_new_infection", map[string]any{"email": "jane.doe@example.com", "promote": true}},
+		},
+	},
+	{
+		name:   "domain_history",
+		query:  tableQuery{"hudsonrock_domain_history", map[string]any{"domain": "example.com"}},
+		config: `snapshot_path = "{{snapshot_path}}"`,
+		// Only hudsonrock_search_by_domain records snapshots
+		setup: []tableQuery{
+			{"hudsonrock_domain_risk", map[string]any{"domain": "example.com"}},
+			{"hudsonrock_search_by_domain", map[string]any{"domain": "example.com"}},
+		},
+	},
+}
+
+func TestTables(t *testing.T) {
+	timeNow = func() time.Time { return testNow }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	for _, tt := range tableTests {
+		t.Run(tt.name, func(t *testing.T) {
+			mock := hudsonrocktest.NewServer()
+			defer mock.Close()
+
+			config := fmt.Sprintf("base_url = %q\n%s", mock.URL, tt.config)
+			config = strings.ReplaceAll(config, "{{snapshot_path}}", filepath.Join(t.TempDir(), "snapshots.db"))
+			server := newTestPluginServer(t, config)
+
+			for _, setup := range tt.setup {
+				executeTable(t, server, setup)
+			}
+			rows := executeTable(t, server, tt.query)
+			compareGolden(t, filepath.Join("testdata", tt.name+".json"), rows)
+		})
+	}
+}
+
+// newTestPluginServer returns an in-process plugin server with a single
+// connection using config.
+func newTestPluginServer(t *testing.T, config string) *grpc.PluginServer {
+	t.Helper()
+	server := plugin.Server(&plugin.ServeOpts{PluginFunc: Plugin})
+	_, err := server.SetAllConnectionConfigs(&proto.SetAllConnectionConfigsRequest{
+		Configs: []*proto.ConnectionConfig{{
+			Connection: testConnection,
+			Plugin:     pluginName,
+			Config:     config,
+		}},
+		MaxCacheSizeMb: -1,
+	})
+	if err != nil {
+		t.Fatalf("SetAllConnectionConfigs: %v", err)
+	}
+	if _, err := server.SetCacheOptions(&proto.SetCacheOptionsRequest{Enabled: false}); err != nil {
+		t.Fatalf("SetCacheOptions: %v", err)
+	}
+	return server
+}
+
+// executeTable runs query through the SDK, selecting every column of the
+// table, and returns the rows with their column values as JSON values.
+// Timestamps are formatted as RFC 3339 strings. The rows are sorted, as
+// fan-out tables stream them in no particular order.
+func executeTable(t *testing.T, server *grpc.PluginServer, query tableQuery) []map[string]any {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+
+	table, ok := Plugin(ctx).TableMap[query.table]
+	if !ok {
+		t.Fatalf("no table %s", query.table)
+	}
+	var columns []string
+	for _, column := range table.Columns {
+		columns = append(columns, column.Name)
+	}
+
+	quals := map[string]*proto.Quals{}
+	for column, value := range query.quals {
+		quals[column] = &proto.Quals{Quals: []*proto.Qual{{
+			FieldName: column,
+			Operator:  &proto.Qual_StringValue{StringValue: "="},
+			Value:     qualValue(t, value),
+		}}}
+	}
+
+	stream := anywhere.NewLocalPluginStream(ctx)
+	server.CallExecuteAsync(&proto.ExecuteRequest{
+		Table:        query.table,
+		QueryContext: &proto.QueryContext{Columns: columns, Quals: quals},
+		Connection:   testConnection,
+		CallId:       query.table,
+		ExecuteConnectionData: map[string]*proto.ExecuteConnectionData{
+			testConnection: {},
+		},
+	}, stream)
+
+	type sortableRow struct {
+		key string
+		row map[string]any
+	}
+	var sortable []sortableRow
+	for {
+		resp, err := stream.Recv()
+		if err != nil {
+			t.Fatalf("executing %s: %v", query.table, err)
+		}
+		if resp == nil {
+			break
+		}
+		row := map[string]any{}
+		for _, name := range columns {
+			row[name] = columnValue(t, resp.Row.Columns[name])
+		}
+		key, _ := json.Marshal(row)
+		sortable = append(sortable, sortableRow{string(key), row})
+	}
+
+	sort.Slice(sortable, func(i, j int) bool { return sortable[i].key < sortable[j].key })
+	rows := make([]map[string]any, 0, len(sortable))
+	for _, r := range sortable {
+		rows = append(rows, r.row)
+	}
+	return rows
+}
+
+func qualValue(t *testing.T, value any) *proto.QualValue {
+	t.Helper()
+	switch v := value.(type) {
+	case string:
+		return &proto.QualValue{Value: &proto.QualValue_StringValue{StringValue: v}}
+	case int64:
+		return &proto.QualValue{Value: &proto.QualValue_Int64Value{Int64Value: v}}
+	case bool:
+		return &proto.QualValue{Value: &proto.QualValue_BoolValue{BoolValue: v}}
+	case json.RawMessage:
+		return &proto.QualValue{Value: &proto.QualValue_JsonbValue{JsonbValue: string(v)}}
+	}
+	t.Fatalf("unsupported qual value %T", value)
+	return nil
+}
+
+func columnValue(t *testing.T, column *proto.Column) any {
+	t.Helper()
+	if column == nil {
+		return nil
+	}
+	switch v := column.Value.(type) {
+	case *proto.Column_NullValue:
+		return nil
+	case *proto.Column_DoubleValue:
+		return v.DoubleValue
+	case *proto.Column_IntValue:
+		return v.IntValue
+	case *proto.Column_StringValue:
+		return v.StringValue
+	case *proto.Column_BoolValue:
+		return v.BoolValue
+	case *proto.Column_JsonValue:
+		var value any
+		if err := json.Unmarshal(v.JsonValue, &value); err != nil {
+			t.Fatalf("invalid JSON column value %s: %v", v.JsonValue, err)
+		}
+		return value
+	case *proto.Column_TimestampValue:
+		return v.TimestampValue.AsTime().UTC().Format(time.RFC3339)
+	}
+	t.Fatalf("unsupported column value %T", column.Value)
+	return nil
+}
+
+// compareGolden compares rows with the golden file at path, or rewrites the
+// file when the -update flag is set.
+func compareGolden(t *testing.T, path string, rows []map[string]any) {
+	t.Helper()
+	got, err := json.MarshalIndent(rows, "", "  ")
+	if err != nil {
+		t.Fatal(err)
+	}
+	got = append(got, '\n')
+
+	if *update {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatal(err)
+		}
+		if err := os.WriteFile(path, got, 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return
+	}
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("reading golden file, run go test -update to create it: %v", err)
+	}
+	var want, normalized []map[string]any
+	if err := json.Unmarshal(data, &want); err != nil {
+		t.Fatalf("invalid golden file %s: %v", path, err)
+	}
+	// Round trip the rows so that numbers compare as float64 on both sides
+	if err := json.Unmarshal(got, &normalized); err != nil {
+		t.Fatal(err)
+	}
+	if len(normalized) != len(want) {
+		t.Fatalf("got %d rows, golden file has %d; run go test -update to accept the change:\n%s", len(normalized), len(want), got)
+	}
+	for i := range want {
+		for column := range mergeKeys(want[i], normalized[i]) {
+			if !reflect.DeepEqual(want[i][column], normalized[i][column]) {
+				t.Errorf("row %d, column %s: got %v, want %v", i, column, normalized[i][column], want[i][column])
+			}
+		}
+	}
+}
+
+func mergeKeys(a, b map[string]any) map[string]struct{} {
+	keys := map[string]struct{}{}
+	for key := range a {
+		keys[key] = struct{}{}
+	}
+	for key := range b {
+		keys[key] = struct{}{}
+	}
+	return keys
+}
@@ -0,0 +1,66 @@
+package hudsonrock
+
+import (
+	"fmt"
+	"time"
+)
+
+// Granularities of the infection timeline.
+const (
+	timelineGranularityDay   = "day"
+	timelineGranularityWeek  = "week"
+	timelineGranularityMonth = "month"
+)
+
+const defaultTimelineGranularity = timelineGranularityMonth
+
+// maxTimelineBuckets bounds the number of periods returned by the infection
+// timeline, about ten years of days. Spans with more periods fail the query.
+const maxTimelineBuckets = 3660
+
+// timelineEarliest is the earliest date placed on the infection timeline.
+// Earlier dates, such as the year 1 placeholders of some records, are left
+// out like unparsable ones.
+var timelineEarliest = time.Date(2000, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// plausibleTimelineDate reports whether t is between timelineEarliest and a
+// day after now.
+func plausibleTimelineDate(t, now time.Time) bool {
+	return !t.Before(timelineEarliest) && !t.After(now.Add(24*time.Hour))
+}
+
+// timelinePeriodStart returns the start of the period containing t. Weeks
+// start on Monday. All periods are in UTC.
+func timelinePeriodStart(t time.Time, granularity string) time.Time {
+	t = t.UTC()
+	day := time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+	switch granularity {
+	case timelineGranularityWeek:
+		offset := (int(day.Weekday()) + 6) % 7
+		return day.AddDate(0, 0, -offset)
+	case timelineGranularityMonth:
+		return time.Date(t.Year(), t.Month(), 1, 0, 0, 0, 0, time.UTC)
+	default:
+		return day
+	}
+}
+
+// timelineNextPeriod returns the start of the period following start.
+func timelineNextPeriod(start time.Time, granularity string) time.Time {
+	switch granularity {
+	case timelineGranularityWeek:
+		return start.AddDate(0, 0, 7)
+	case timelineGranularityMonth:
+		return start.AddDate(0, 1, 0)
+	default:
+		return start.AddDate(0, 0, 1)
+	}
+}
+
+func validateTimelineGranularity(granularity string) error {
+	switch granularity {
+	case timelineGranularityDay, timelineGranularityWeek, timelineGranularityMonth:
+		return nil
+	}
+	return fmt.Errorf("granularity must be %s, %s or %s, got %q", timelineGranularityDay, timelineGranularityWeek, timelineGranularityMonth, granularity)
+}
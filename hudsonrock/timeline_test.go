@@ -0,0 +1,74 @@
+package hudsonrock
+
+import (
+	"reflect"
+	"testing"
+	"time"
+)
+
+func TestValidateTimelineGranularity(t *testing.T) {
+	for _, granularity := range []string{"day", "week", "month"} {
+		if err := validateTimelineGranularity(granularity); err != nil {
+			t.Errorf("validateTimelineGranularity(%q) = %v", granularity, err)
+		}
+	}
+	// The qual is echoed back in every row, so values Postgres would not
+	// match again are rejected rather than normalized
+	for _, granularity := range []string{"Week", "MONTH", " day", "year", ""} {
+		if err := validateTimelineGranularity(granularity); err == nil {
+			t.Errorf("validateTimelineGranularity(%q) succeeded", granularity)
+		}
+	}
+}
+
+func TestBuildInfectionTimeline(t *testing.T) {
+	timeNow = func() time.Time { return testNow }
+	t.Cleanup(func() { timeNow = time.Now })
+
+	infections := []Infection{
+		{InfectionID: "a", DateCompromised: "2024-07-30T10:00:00.000Z", StealerFamily: "RedLine"},
+		{InfectionID: "b", DateCompromised: "2024-08-02T10:00:00.000Z", StealerFamily: "Lumma"},
+		{InfectionID: "c", DateCompromised: "2024-08-03T10:00:00.000Z"},
+		// Implausible and missing dates are left out
+		{InfectionID: "d", DateCompromised: "0001-01-01T00:00:00.000Z"},
+		{InfectionID: "e", DateCompromised: "2099-01-01T00:00:00.000Z"},
+		{InfectionID: "f"},
+	}
+	markers := []TimelineDomainMarker{
+		{Domain: "example.com", Marker: timelineMarkerLastUserCompromised, Time: time.Date(2024, time.September, 20, 0, 0, 0, 0, time.UTC)},
+		{Domain: "example.com", Marker: timelineMarkerLastEmployeeCompromised, Time: time.Time{}},
+	}
+
+	buckets, err := buildInfectionTimeline(infections, markers, nil, timelineGranularityMonth)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, bucket := range buckets {
+		got = append(got, bucket.PeriodStart.Format(time.DateOnly))
+	}
+	if want := []string{"2024-07-01", "2024-08-01", "2024-09-01"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("periods = %q, want %q", got, want)
+	}
+	if b := buckets[1]; b.InfectionCount != 2 || b.FamilyCount != 1 || b.Granularity != timelineGranularityMonth {
+		t.Errorf("August bucket = %+v", b)
+	}
+	if b := buckets[2]; b.InfectionCount != 0 || len(b.DomainMarkers) != 1 {
+		t.Errorf("September bucket = %+v", b)
+	}
+
+	// Spans with too many periods fail rather than skip the empty ones
+	spread := []Infection{
+		{InfectionID: "old", DateCompromised: "2000-01-01"},
+		{InfectionID: "new", DateCompromised: "2024-09-30"},
+	}
+	if _, err := buildInfectionTimeline(spread, nil, nil, timelineGranularityDay); err == nil {
+		t.Error("daily timeline over 24 years succeeded")
+	}
+	if buckets, err := buildInfectionTimeline(spread, nil, nil, timelineGranularityMonth); err != nil || len(buckets) != 24*12+9 {
+		t.Errorf("monthly buckets over 24 years = %d, %v, want %d", len(buckets), err, 24*12+9)
+	}
+	if buckets, err := buildInfectionTimeline(infections[3:], nil, nil, timelineGranularityDay); err != nil || buckets != nil {
+		t.Errorf("timeline without plausible dates = %+v, %v, want nil", buckets, err)
+	}
+}
@@ -0,0 +1,166 @@
+package hudsonrock
+
+import (
+	"context"
+	"reflect"
+	"time"
+
+	"github.com/turbot/steampipe-plugin-hudsonrock/api"
+	"github.com/turbot/steampipe-plugin-sdk/v5/plugin/transform"
+)
+
+// timeNow returns the current time. Recency classes, risk scores and
+// baselines depend on it, so table tests pin it.
+var timeNow = time.Now
+
+//// TRANSFORM FUNCTIONS
+
+// nullIfEmptySlice is transform.NullIfEmptySliceValue without its panic on
+// nil pointers, which the row structs use for NULL values.
+func nullIfEmptySlice(ctx context.Context, d *transform.TransformData) (interface{}, error) {
+	if v := reflect.ValueOf(d.Value); v.Kind() == reflect.Pointer && v.IsNil() {
+		return nil, nil
+	}
+	return transform.NullIfEmptySliceValue(ctx, d)
+}
+
+func emailDomain(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	email, ok := d.Value.(string)
+	if !ok {
+		return nil, nil
+	}
+	domain := api.EmailDomain(email)
+	if domain == "" {
+		return nil, nil
+	}
+	return domain, nil
+}
+
+func isFreemail(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	email, ok := d.Value.(string)
+	if !ok {
+		return nil, nil
+	}
+	domain := api.EmailDomain(email)
+	if domain == "" {
+		return nil, nil
+	}
+	return api.IsFreemailDomain(domain), nil
+}
+
+func corporateLoginDomains(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	logins, ok := d.Value.([]string)
+	if !ok {
+		return nil, nil
+	}
+	return api.CorporateLoginDomains(logins), nil
+}
+
+func infectionID(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	stealer, ok := d.Value.(interface{ InfectionID() string })
+	if !ok {
+		return nil, nil
+	}
+	return stealer.InfectionID(), nil
+}
+
+// malwarePathComponent returns the MalwarePathInfo field named by the
+// transform param for the malware path in d.Value.
+func malwarePathComponent(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	path, ok := d.Value.(string)
+	if !ok || path == "" {
+		return nil, nil
+	}
+	info := api.ParseMalwarePath(path)
+
+	var value string
+	switch d.Param.(string) {
+	case "Drive":
+		value = info.Drive
+	case "Directory":
+		value = info.Directory
+	case "Filename":
+		value = info.Filename
+	case "Extension":
+		value = info.Extension
+	case "ProfileUser":
+		value = info.ProfileUser
+	case "LocationClass":
+		value = info.LocationClass
+	}
+	if value == "" {
+		return nil, nil
+	}
+	return value, nil
+}
+
+// operatingSystemComponent returns the OSInfo field named by the transform
+// param for the operating system string in d.Value.
+func operatingSystemComponent(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	operatingSystem, ok := d.Value.(string)
+	if !ok || operatingSystem == "" {
+		return nil, nil
+	}
+	info := api.ParseOperatingSystem(operatingSystem)
+
+	var value string
+	switch d.Param.(string) {
+	case "Family":
+		value = info.Family
+	case "Version":
+		value = info.Version
+	case "Edition":
+		value = info.Edition
+	case "Architecture":
+		value = info.Architecture
+	case "IsEnterpriseEdition":
+		return info.IsEnterpriseEdition, nil
+	}
+	if value == "" {
+		return nil, nil
+	}
+	return value, nil
+}
+
+func antivirusVendors(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	names, ok := d.Value.([]string)
+	if !ok {
+		return nil, nil
+	}
+	return api.AntivirusVendors(names), nil
+}
+
+func hasEDR(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	names, ok := d.Value.([]string)
+	if !ok {
+		return nil, nil
+	}
+	return api.HasEDR(names), nil
+}
+
+func defenderOnly(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	names, ok := d.Value.([]string)
+	if !ok {
+		return nil, nil
+	}
+	return api.IsDefenderOnly(names), nil
+}
+
+type antivirusProductVendor struct {
+	api.AntivirusProduct
+	Count int `json:"count"`
+}
+
+// antivirusProductVendors resolves the domain-level antivirus product list
+// against the vendor catalog.
+func antivirusProductVendors(_ context.Context, d *transform.TransformData) (interface{}, error) {
+	products, ok := d.Value.([]api.AVProduct)
+	if !ok || len(products) == 0 {
+		return nil, nil
+	}
+	result := make([]antivirusProductVendor, 0, len(products))
+	for _, product := range products {
+		result = append(result, antivirusProductVendor{api.NormalizeAntivirus(product.Name), int(product.Count)})
+	}
+	return result, nil
+}
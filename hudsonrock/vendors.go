@@ -0,0 +1,78 @@
+package hudsonrock
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+)
+
+// vendor is a third-party domain in the vendor risk portfolio.
+type vendor struct {
+	Domain string
+	Name   string
+}
+
+// loadVendors returns the vendors listed in the connection config followed by
+// those in vendors_file, without duplicate domains.
+//
+// vendors_file is a CSV file whose first column is the vendor domain and
+// optional second column is the vendor name. A header row starting with
+// "domain" is skipped.
+func loadVendors(config HudsonRockConfig) ([]vendor, error) {
+	var vendors []vendor
+	seen := map[string]struct{}{}
+	add := func(v vendor) {
+		v.Domain = strings.ToLower(strings.TrimSpace(v.Domain))
+		v.Name = strings.TrimSpace(v.Name)
+		if v.Domain == "" {
+			return
+		}
+		if _, ok := seen[v.Domain]; ok {
+			return
+		}
+		seen[v.Domain] = struct{}{}
+		vendors = append(vendors, v)
+	}
+
+	for _, domain := range config.Vendors {
+		add(vendor{Domain: domain})
+	}
+
+	if config.VendorsFile == nil || *config.VendorsFile == "" {
+		return vendors, nil
+	}
+	path, err := expandPath(*config.VendorsFile)
+	if err != nil {
+		return nil, err
+	}
+	file, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open vendors_file: %w", err)
+	}
+	defer file.Close()
+
+	reader := csv.NewReader(file)
+	reader.FieldsPerRecord = -1
+	reader.Comment = '#'
+	for line := 1; ; line++ {
+		record, err := reader.Read()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("failed to read vendors_file %s: %w", path, err)
+		}
+		if line == 1 && strings.EqualFold(strings.TrimSpace(record[0]), "domain") {
+			continue
+		}
+		v := vendor{Domain: record[0]}
+		if len(record) > 1 {
+			v.Name = record[1]
+		}
+		add(v)
+	}
+	return vendors, nil
+}
@@ -0,0 +1,46 @@
+package hudsonrock
+
+// Asset type used for domains on the watchlist, alongside the email, IP and
+// username lookup types.
+const assetTypeDomain = "domain"
+
+// identifier is a typed email, domain, IP address or username.
+type identifier struct {
+	Type  string
+	Value string
+}
+
+// watchlistAssets returns the assets on the watchlist in config order:
+// emails, domains, IPs and then usernames. Empty values are skipped.
+func watchlistAssets(config *WatchlistConfig) []identifier {
+	if config == nil {
+		return nil
+	}
+
+	var assets []identifier
+	for _, group := range []struct {
+		assetType string
+		values    []string
+	}{
+		{lookupTypeEmail, config.Emails},
+		{assetTypeDomain, config.Domains},
+		{lookupTypeIP, config.IPs},
+		{lookupTypeUsername, config.Usernames},
+	} {
+		for _, value := range group.values {
+			if value != "" {
+				assets = append(assets, identifier{group.assetType, value})
+			}
+		}
+	}
+	return assets
+}
+
+// watchlistMaxConcurrency returns the number of lookups the watchlist may run
+// at once.
+func watchlistMaxConcurrency(config *WatchlistConfig) int {
+	if config == nil || config.MaxConcurrency == nil {
+		return defaultMaxConcurrency
+	}
+	return *config.MaxConcurrency
+}
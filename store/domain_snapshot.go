@@ -0,0 +1,110 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"time"
+)
+
+// DomainSnapshot is the state of a domain search result at a point in time.
+type DomainSnapshot struct {
+	Domain                  string
+	SnapshotTime            time.Time
+	Total                   int64
+	TotalStealers           int64
+	Employees               int64
+	Users                   int64
+	ThirdParties            int64
+	LastEmployeeCompromised string
+	LastUserCompromised     string
+	StealerFamilies         map[string]int64
+	// Raw is the domain search response the snapshot was taken from
+	Raw json.RawMessage
+}
+
+// RecordDomainSnapshot stores a domain snapshot.
+func (s *Store) RecordDomainSnapshot(ctx context.Context, snapshot DomainSnapshot) error {
+	families, err := json.Marshal(snapshot.StealerFamilies)
+	if err != nil {
+		return err
+	}
+	if snapshot.StealerFamilies == nil {
+		families = []byte("{}")
+	}
+
+	_, err = s.db.ExecContext(ctx,
+		`insert or replace into domain_snapshot (
+			domain, snapshot_time, total, total_stealers, employees, users, third_parties,
+			last_employee_compromised, last_user_compromised, stealer_families, raw
+		) values (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`,
+		snapshot.Domain,
+		snapshot.SnapshotTime.UTC().Format(timeFormat),
+		snapshot.Total,
+		snapshot.TotalStealers,
+		snapshot.Employees,
+		snapshot.Users,
+		snapshot.ThirdParties,
+		nullString(snapshot.LastEmployeeCompromised),
+		nullString(snapshot.LastUserCompromised),
+		string(families),
+		nullString(string(snapshot.Raw)),
+	)
+	if err != nil {
+		return fmt.Errorf("failed to record snapshot for domain %s: %w", snapshot.Domain, err)
+	}
+	return nil
+}
+
+// DomainSnapshots returns the snapshots of a domain in chronological order.
+// If domain is empty, the snapshots of all domains are returned, ordered by
+// domain and then time.
+func (s *Store) DomainSnapshots(ctx context.Context, domain string) ([]DomainSnapshot, error) {
+	query := `select domain, snapshot_time, total, total_stealers, employees, users, third_parties,
+			last_employee_compromised, last_user_compromised, stealer_families, raw
+		from domain_snapshot`
+	var args []interface{}
+	if domain != "" {
+		query += ` where domain = ?`
+		args = append(args, domain)
+	}
+	query += ` order by domain, snapshot_time`
+
+	rows, err := s.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list domain snapshots: %w", err)
+	}
+	defer rows.Close()
+
+	var snapshots []DomainSnapshot
+	for rows.Next() {
+		var snapshot DomainSnapshot
+		var snapshotTime, families string
+		var lastEmployee, lastUser, raw sql.NullString
+		if err := rows.Scan(
+			&snapshot.Domain, &snapshotTime, &snapshot.Total, &snapshot.TotalStealers,
+			&snapshot.Employees, &snapshot.Users, &snapshot.ThirdParties,
+			&lastEmployee, &lastUser, &families, &raw,
+		); err != nil {
+			return nil, fmt.Errorf("failed to read domain snapshot: %w", err)
+		}
+		if snapshot.SnapshotTime, err = time.Parse(timeFormat, snapshotTime); err != nil {
+			return nil, fmt.Errorf("invalid snapshot time %q: %w", snapshotTime, err)
+		}
+		if err := json.Unmarshal([]byte(families), &snapshot.StealerFamilies); err != nil {
+			return nil, fmt.Errorf("invalid stealer families for snapshot %s: %w", snapshotTime, err)
+		}
+		snapshot.LastEmployeeCompromised = lastEmployee.String
+		snapshot.LastUserCompromised = lastUser.String
+		if raw.Valid {
+			snapshot.Raw = json.RawMessage(raw.String)
+		}
+		snapshots = append(snapshots, snapshot)
+	}
+	return snapshots, rows.Err()
+}
+
+func nullString(value string) sql.NullString {
+	return sql.NullString{String: value, Valid: value != ""}
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// RecordSightings records that the given infections were seen at the given
+// time and returns the time each one was first seen. Infections seen before
+// keep their original first seen time.
+func (s *Store) RecordSightings(ctx context.Context, infectionIDs []string, seen time.Time) (map[string]time.Time, error) {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return nil, fmt.Errorf("failed to record infection sightings: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	firstSeen := make(map[string]time.Time, len(infectionIDs))
+	for _, id := range infectionIDs {
+		if _, err := tx.ExecContext(ctx,
+			`insert or ignore into infection_sighting (infection_id, first_seen) values (?, ?)`,
+			id, seen.UTC().Format(timeFormat),
+		); err != nil {
+			return nil, fmt.Errorf("failed to record sighting of infection %s: %w", id, err)
+		}
+
+		var value string
+		if err := tx.QueryRowContext(ctx,
+			`select first_seen from infection_sighting where infection_id = ?`, id,
+		).Scan(&value); err != nil {
+			return nil, fmt.Errorf("failed to read sighting of infection %s: %w", id, err)
+		}
+		t, err := time.Parse(timeFormat, value)
+		if err != nil {
+			return nil, fmt.Errorf("invalid first seen time %q: %w", value, err)
+		}
+		firstSeen[id] = t
+	}
+
+	if err := tx.Commit(); err != nil {
+		return nil, fmt.Errorf("failed to record infection sightings: %w", err)
+	}
+	return firstSeen, nil
+}
+
+// InBaseline returns the subset of the given infections that are part of the
+// baseline.
+func (s *Store) InBaseline(ctx context.Context, infectionIDs []string) (map[string]bool, error) {
+	known := make(map[string]bool)
+	for _, id := range infectionIDs {
+		var count int
+		if err := s.db.QueryRowContext(ctx,
+			`select count(*) from infection_baseline where infection_id = ?`, id,
+		).Scan(&count); err != nil {
+			return nil, fmt.Errorf("failed to read baseline: %w", err)
+		}
+		if count > 0 {
+			known[id] = true
+		}
+	}
+	return known, nil
+}
+
+// PromoteToBaseline adds the given infections to the baseline so they are no
+// longer reported as new.
+func (s *Store) PromoteToBaseline(ctx context.Context, infectionIDs []string, promoted time.Time) error {
+	tx, err := s.db.BeginTx(ctx, nil)
+	if err != nil {
+		return fmt.Errorf("failed to promote infections to baseline: %w", err)
+	}
+	defer tx.Rollback() //nolint:errcheck // no-op after commit
+
+	for _, id := range infectionIDs {
+		if _, err := tx.ExecContext(ctx,
+			`insert or ignore into infection_baseline (infection_id, promoted_at) values (?, ?)`,
+			id, promoted.UTC().Format(timeFormat),
+		); err != nil {
+			return fmt.Errorf("failed to promote infection %s to baseline: %w", id, err)
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return fmt.Errorf("failed to promote infections to baseline: %w", err)
+	}
+	return nil
+}
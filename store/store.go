@@ -0,0 +1,91 @@
+// Package store persists local state for the Hudson Rock plugin, such as
+// domain snapshots and infection baselines, in a SQLite database file.
+package store
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+
+	_ "modernc.org/sqlite"
+)
+
+// timeFormat is the layout used to store timestamps. It sorts
+// lexicographically in chronological order.
+const timeFormat = "2006-01-02T15:04:05.000000000Z07:00"
+
+// schema is applied every time the store is opened, so statements must be
+// idempotent.
+var schema = []string{
+	`create table if not exists domain_snapshot (
+		domain text not null,
+		snapshot_time text not null,
+		total integer not null,
+		total_stealers integer not null,
+		employees integer not null,
+		users integer not null,
+		third_parties integer not null,
+		last_employee_compromised text,
+		last_user_compromised text,
+		stealer_families text not null,
+		raw text,
+		primary key (domain, snapshot_time)
+	)`,
+	`create table if not exists infection_sighting (
+		infection_id text primary key,
+		first_seen text not null
+	)`,
+	`create table if not exists infection_baseline (
+		infection_id text primary key,
+		promoted_at text not null
+	)`,
+}
+
+// Store is a local SQLite database holding plugin state.
+type Store struct {
+	db   *sql.DB
+	path string
+}
+
+// Open opens the SQLite database at path, creating the file, its parent
+// directory and the schema if needed.
+func Open(ctx context.Context, path string) (*Store, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("failed to create store directory: %w", err)
+	}
+
+	// Steampipe may run several hydrate calls at once, so wait on locks
+	// rather than failing with SQLITE_BUSY.
+	dsn := (&url.URL{
+		Scheme:   "file",
+		Path:     path,
+		RawQuery: "_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)",
+	}).String()
+	db, err := sql.Open("sqlite", dsn)
+	if err != nil {
+		return nil, fmt.Errorf("failed to open store %s: %w", path, err)
+	}
+	db.SetMaxOpenConns(1)
+
+	for _, statement := range schema {
+		if _, err := db.ExecContext(ctx, statement); err != nil {
+			db.Close()
+			return nil, fmt.Errorf("failed to initialize store %s: %w", path, err)
+		}
+	}
+
+	return &Store{db: db, path: path}, nil
+}
+
+// Path returns the path of the database file.
+func (s *Store) Path() string {
+	return s.path
+}
+
+// Close closes the database.
+func (s *Store) Close() error {
+	return s.db.Close()
+}
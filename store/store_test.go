@@ -0,0 +1,231 @@
+package store
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"testing"
+	"time"
+)
+
+var (
+	day1 = time.Date(2024, time.September, 1, 8, 30, 0, 123456789, time.UTC)
+	day2 = day1.Add(24 * time.Hour)
+	day3 = day2.Add(24 * time.Hour)
+)
+
+// openTestStore opens a store in a new directory below a temporary one, so
+// that Open has to create it.
+func openTestStore(t *testing.T) *Store {
+	t.Helper()
+	s, err := Open(context.Background(), filepath.Join(t.TempDir(), "state", "snapshots.db"))
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	t.Cleanup(func() { s.Close() })
+	return s
+}
+
+func TestOpenCreatesSchema(t *testing.T) {
+	ctx := context.Background()
+	path := filepath.Join(t.TempDir(), "a", "b", "snapshots.db")
+
+	s, err := Open(ctx, path)
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	if s.Path() != path {
+		t.Errorf("Path = %s, want %s", s.Path(), path)
+	}
+	if err := s.RecordDomainSnapshot(ctx, DomainSnapshot{Domain: "example.com", SnapshotTime: day1, Total: 1}); err != nil {
+		t.Fatalf("RecordDomainSnapshot: %v", err)
+	}
+	if err := s.Close(); err != nil {
+		t.Fatalf("Close: %v", err)
+	}
+
+	// Reopening applies the schema again and keeps the data
+	s, err = Open(ctx, path)
+	if err != nil {
+		t.Fatalf("reopen: %v", err)
+	}
+	defer s.Close()
+	for _, table := range []string{"domain_snapshot", "infection_sighting", "infection_baseline"} {
+		var name string
+		if err := s.db.QueryRowContext(ctx, `select name from sqlite_master where type = 'table' and name = ?`, table).Scan(&name); err != nil {
+			t.Errorf("table %s: %v", table, err)
+		}
+	}
+	snapshots, err := s.DomainSnapshots(ctx, "example.com")
+	if err != nil || len(snapshots) != 1 {
+		t.Errorf("after reopening got %d snapshots, %v", len(snapshots), err)
+	}
+}
+
+func TestDomainSnapshots(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+
+	recorded := []DomainSnapshot{
+		{Domain: "example.com", SnapshotTime: day2, Total: 12, TotalStealers: 7, Employees: 3, Users: 9, ThirdParties: 1, LastEmployeeCompromised: "2024-08-30T10:00:00.000Z", StealerFamilies: map[string]int64{"RedLine": 5, "Lumma": 2}, Raw: json.RawMessage(`{"total":12}`)},
+		{Domain: "example.com", SnapshotTime: day1, Total: 10, TotalStealers: 6, Employees: 2, Users: 8, StealerFamilies: map[string]int64{"RedLine": 6}},
+		{Domain: "acme.example", SnapshotTime: day3, Total: 1},
+	}
+	for _, snapshot := range recorded {
+		if err := s.RecordDomainSnapshot(ctx, snapshot); err != nil {
+			t.Fatalf("RecordDomainSnapshot: %v", err)
+		}
+	}
+
+	got, err := s.DomainSnapshots(ctx, "example.com")
+	if err != nil {
+		t.Fatalf("DomainSnapshots: %v", err)
+	}
+	// Chronological order, and nil families and empty strings read back as
+	// empty values
+	want := []DomainSnapshot{recorded[1], recorded[0]}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("DomainSnapshots(example.com) = %+v\nwant %+v", got, want)
+	}
+
+	all, err := s.DomainSnapshots(ctx, "")
+	if err != nil {
+		t.Fatalf("DomainSnapshots: %v", err)
+	}
+	var order []string
+	for _, snapshot := range all {
+		order = append(order, snapshot.Domain+" "+snapshot.SnapshotTime.Format(time.DateOnly))
+	}
+	if want := []string{"acme.example 2024-09-03", "example.com 2024-09-01", "example.com 2024-09-02"}; !reflect.DeepEqual(order, want) {
+		t.Errorf("DomainSnapshots() order = %q, want %q", order, want)
+	}
+	if all[0].StealerFamilies == nil || len(all[0].StealerFamilies) != 0 {
+		t.Errorf("nil stealer families read back as %#v, want an empty map", all[0].StealerFamilies)
+	}
+
+	// Empty compromise times are stored as NULL
+	var lastUser sql.NullString
+	if err := s.db.QueryRowContext(ctx, `select last_user_compromised from domain_snapshot where domain = 'example.com' and total = 12`).Scan(&lastUser); err != nil || lastUser.Valid {
+		t.Errorf("last_user_compromised = %+v, %v, want NULL", lastUser, err)
+	}
+
+	// A snapshot at the same time replaces the previous one
+	replaced := recorded[1]
+	replaced.Total = 11
+	if err := s.RecordDomainSnapshot(ctx, replaced); err != nil {
+		t.Fatalf("RecordDomainSnapshot: %v", err)
+	}
+	got, _ = s.DomainSnapshots(ctx, "example.com")
+	if len(got) != 2 || got[0].Total != 11 {
+		t.Errorf("after replacing got %+v", got)
+	}
+
+	if got, err := s.DomainSnapshots(ctx, "unknown.example"); err != nil || len(got) != 0 {
+		t.Errorf("DomainSnapshots(unknown.example) = %+v, %v", got, err)
+	}
+}
+
+func TestInfectionBaseline(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+
+	firstSeen, err := s.RecordSightings(ctx, []string{"a", "b"}, day1)
+	if err != nil {
+		t.Fatalf("RecordSightings: %v", err)
+	}
+	if want := map[string]time.Time{"a": day1, "b": day1}; !reflect.DeepEqual(firstSeen, want) {
+		t.Errorf("first sightings = %v, want %v", firstSeen, want)
+	}
+
+	// Infections seen again keep their first sighting
+	firstSeen, err = s.RecordSightings(ctx, []string{"b", "c"}, day2)
+	if err != nil {
+		t.Fatalf("RecordSightings: %v", err)
+	}
+	if want := map[string]time.Time{"b": day1, "c": day2}; !reflect.DeepEqual(firstSeen, want) {
+		t.Errorf("second sightings = %v, want %v", firstSeen, want)
+	}
+
+	known, err := s.InBaseline(ctx, []string{"a", "b", "c"})
+	if err != nil || len(known) != 0 {
+		t.Errorf("InBaseline before promoting = %v, %v", known, err)
+	}
+	if err := s.PromoteToBaseline(ctx, []string{"a", "c"}, day2); err != nil {
+		t.Fatalf("PromoteToBaseline: %v", err)
+	}
+	// Promoting twice is a no-op
+	if err := s.PromoteToBaseline(ctx, []string{"a"}, day3); err != nil {
+		t.Fatalf("PromoteToBaseline: %v", err)
+	}
+	known, err = s.InBaseline(ctx, []string{"a", "b", "c", "d"})
+	if err != nil {
+		t.Fatalf("InBaseline: %v", err)
+	}
+	if want := map[string]bool{"a": true, "c": true}; !reflect.DeepEqual(known, want) {
+		t.Errorf("InBaseline = %v, want %v", known, want)
+	}
+}
+
+// TestConcurrentAccess writes from several goroutines through one store, as
+// concurrent hydrate calls do, and through a second store on the same file,
+// as a second plugin process does.
+func TestConcurrentAccess(t *testing.T) {
+	ctx := context.Background()
+	s := openTestStore(t)
+	other, err := Open(ctx, s.Path())
+	if err != nil {
+		t.Fatalf("Open: %v", err)
+	}
+	defer other.Close()
+
+	const writers, writes = 8, 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers)
+	for w := 0; w < writers; w++ {
+		wg.Add(1)
+		go func(w int) {
+			defer wg.Done()
+			store := s
+			if w%2 == 1 {
+				store = other
+			}
+			for i := 0; i < writes; i++ {
+				snapshot := DomainSnapshot{Domain: fmt.Sprintf("d%d.example", w), SnapshotTime: day1.Add(time.Duration(i) * time.Minute), Total: int64(i)}
+				if err := store.RecordDomainSnapshot(ctx, snapshot); err != nil {
+					errs <- err
+					return
+				}
+				id := fmt.Sprintf("%d-%d", w, i)
+				if _, err := store.RecordSightings(ctx, []string{id}, day1); err != nil {
+					errs <- err
+					return
+				}
+				if err := store.PromoteToBaseline(ctx, []string{id}, day1); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Error(err)
+	}
+
+	all, err := s.DomainSnapshots(ctx, "")
+	if err != nil {
+		t.Fatalf("DomainSnapshots: %v", err)
+	}
+	if len(all) != writers*writes {
+		t.Errorf("got %d snapshots, want %d", len(all), writers*writes)
+	}
+	var baseline int
+	if err := s.db.QueryRowContext(ctx, `select count(*) from infection_baseline`).Scan(&baseline); err != nil || baseline != writers*writes {
+		t.Errorf("baseline has %d infections, %v, want %d", baseline, err, writers*writes)
+	}
+}